@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	cli "github.com/jawher/mow.cli"
+)
+
+// ANSI colours used by the human diff output
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+// diffReport is the document written by `tjv diff --format json`.
+type diffReport struct {
+	Left        string           `json:"left"`
+	Right       string           `json:"right"`
+	Identical   bool             `json:"identical"`
+	Differences []diffReportItem `json:"differences"`
+}
+
+type diffReportItem struct {
+	Op   string          `json:"op"`
+	Path string          `json:"path"`
+	Key  string          `json:"key"`
+	From json.RawMessage `json:"from,omitempty"`
+	To   json.RawMessage `json:"to,omitempty"`
+}
+
+// diffCommand configures the headless `diff` subcommand. It exits with 0 when
+// the documents are equal, 1 when they differ and 2 on errors.
+func diffCommand(cmd *cli.Cmd) {
+	cmd.Spec = "[OPTIONS] LEFT RIGHT"
+
+	var (
+		format     = cmd.StringOpt("f format", "human", "Output format: human, patch (RFC 6902) or json")
+		noColor    = cmd.BoolOpt("no-color", false, "Disable coloured human output")
+		ignore     = cmd.StringsOpt("i ignore", nil, "Path pattern to leave out, e.g. **.timestamp or /metadata/generation (repeatable)")
+		arrayMatch = cmd.StringOpt("a array-match", "index", "How array elements are paired: index, lcs or key=FIELD")
+		leftPath   = cmd.StringArg("LEFT", "", "First JSON file, or - for standard input")
+		rightPath  = cmd.StringArg("RIGHT", "", "Second JSON file, or - for standard input")
+	)
+
+	cmd.Action = func() {
+		options, err := newDiffOptions(*ignore, *arrayMatch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tjv diff:", err)
+			cli.Exit(2)
+		}
+
+		if *leftPath == "-" && *rightPath == "-" {
+			fmt.Fprintln(os.Stderr, "tjv diff: standard input can only be read once; give a file for LEFT or RIGHT")
+			cli.Exit(2)
+		}
+
+		left, err := readDiffInput(*leftPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tjv diff:", err)
+			cli.Exit(2)
+		}
+		right, err := readDiffInput(*rightPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tjv diff:", err)
+			cli.Exit(2)
+		}
+
+		ops := diffValues(nil, left, right, options)
+
+		var data []byte
+		switch *format {
+		case "human":
+			writeHumanDiff(os.Stdout, ops, !*noColor && isTerminal(os.Stdout))
+		case "patch":
+			if data, err = jsonPatch(ops); err == nil {
+				_, err = fmt.Fprintln(os.Stdout, string(data))
+			}
+		case "json":
+			if data, err = diffReportJSON(*leftPath, *rightPath, ops); err == nil {
+				_, err = fmt.Fprintln(os.Stdout, string(data))
+			}
+		default:
+			err = fmt.Errorf("unknown output format %q (want human, patch or json)", *format)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tjv diff:", err)
+			cli.Exit(2)
+		}
+
+		if len(ops) > 0 {
+			cli.Exit(1)
+		}
+	}
+}
+
+func diffReportJSON(leftPath, rightPath string, ops []diffOp) ([]byte, error) {
+	report := diffReport{
+		Left:        leftPath,
+		Right:       rightPath,
+		Identical:   len(ops) == 0,
+		Differences: make([]diffReportItem, 0, len(ops)),
+	}
+	for _, op := range ops {
+		item := diffReportItem{Op: op.op, Path: op.path.pointer(), Key: op.path.String()}
+		var err error
+		if op.op != "add" {
+			if item.From, err = json.Marshal(op.oldValue); err != nil {
+				return nil, err
+			}
+		}
+		if op.op != "remove" {
+			if item.To, err = json.Marshal(op.newValue); err != nil {
+				return nil, err
+			}
+		}
+		report.Differences = append(report.Differences, item)
+	}
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// isTerminal reports whether f is attached to a character device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// parseStdin decodes standard input in the format it holds: gzip data is
+// recognized by its magic number, and content that is not a single JSON
+// document but has a JSON document on every line is read as JSON Lines.
+func parseStdin(content []byte) (interface{}, error) {
+	const source = "standard input"
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		var err error
+		if content, err = gunzip(source, content); err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := parseJSON(source, content)
+	if err == nil {
+		return value, nil
+	}
+	if values, linesErr := parseLines(source, content); linesErr == nil && len(values) > 0 {
+		return values, nil
+	}
+	return nil, err
+}
+
+// readDiffInput parses a diff operand; "-" reads standard input, which is
+// decoded like a file of the format it holds.
+func readDiffInput(path string) (interface{}, error) {
+	if path != "-" {
+		return readJSONFile(path)
+	}
+
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("error reading standard input: %w", err)
+	}
+	return parseStdin(content)
+}
+
+// summarizeValue renders a value on a single line for the human diff output
+func summarizeValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+func writeHumanDiff(w io.Writer, ops []diffOp, color bool) {
+	paint := func(code, text string) string {
+		if !color {
+			return text
+		}
+		return code + text + ansiReset
+	}
+
+	for _, op := range ops {
+		switch op.op {
+		case "add":
+			fmt.Fprintln(w, paint(ansiGreen, "+ "+op.path.String()+": "+summarizeValue(op.newValue)))
+		case "remove":
+			fmt.Fprintln(w, paint(ansiRed, "- "+op.path.String()+": "+summarizeValue(op.oldValue)))
+		case "replace":
+			fmt.Fprintln(w, paint(ansiYellow, "~ "+op.path.String()+": "+summarizeValue(op.oldValue)+" → "+summarizeValue(op.newValue)))
+		}
+	}
+}
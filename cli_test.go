@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestParseStdin(t *testing.T) {
+	gzipped := func(text string) string {
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		writer.Write([]byte(text))
+		writer.Close()
+		return buffer.String()
+	}
+	tests := []struct {
+		content string
+		want    string // empty when the content is invalid
+	}{
+		{`{"a":1}`, `{"a":1}`},
+		{"{\n  \"a\": 1\n}\n", `{"a":1}`},
+		{"1\n", `1`},
+		{"{\"a\":1}\n\n{\"a\":2}\n", `[{"a":1},{"a":2}]`},
+		{"{\"a\":1}\nnope\n", ""},
+		{"", ""},
+		{gzipped(`{"a":1}`), `{"a":1}`},
+		{gzipped("1\n2\n"), `[1,2]`},
+		{"\x1f\x8bnot gzip", ""},
+	}
+	for _, test := range tests {
+		got, err := parseStdin([]byte(test.content))
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("parseStdin(%q) = %v, want an error", test.content, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseStdin(%q): %v", test.content, err)
+		} else if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseStdin(%q) = %v, want %v", test.content, got, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// Widest file name before the columns of the file list
+const maxFileNameWidth = 40
+
+// fileColumn is an optional column of the file list
+type fileColumn struct {
+	name  string
+	label string
+	width int
+	// value renders the column, or returns "" when it is not known yet
+	value func(validation fileValidation) string
+}
+
+// fileSortOrder is how the files of a directory are ordered in the file list
+type fileSortOrder int
+
+const (
+	sortByName fileSortOrder = iota
+	sortBySize
+	sortByModTime
+	sortByStatus
+)
+
+// Names of the sort orders in config.json and the file list title
+var sortOrderNames = [...]string{
+	sortByName:    "name",
+	sortBySize:    "size",
+	sortByModTime: "mtime",
+	sortByStatus:  "status",
+}
+
+// fileColumns are the columns the file list can show, in display order
+var fileColumns = []fileColumn{
+	{name: "size", label: "Size", width: 8, value: func(v fileValidation) string {
+		if v.modTime.IsZero() {
+			return ""
+		}
+		return formatSize(v.size)
+	}},
+	{name: "mtime", label: "Modified", width: 16, value: func(v fileValidation) string {
+		if v.modTime.IsZero() {
+			return ""
+		}
+		return v.modTime.Format("2006-01-02 15:04")
+	}},
+	{name: "type", label: "Top-level type", width: 6, value: func(v fileValidation) string {
+		return v.topType
+	}},
+	{name: "count", label: "Element count", width: 6, value: func(v fileValidation) string {
+		if v.topType != "object" && v.topType != "array" {
+			return ""
+		}
+		return strconv.Itoa(v.count)
+	}},
+	{name: "depth", label: "Max depth", width: 5, value: func(v fileValidation) string {
+		if v.status != statusValid {
+			return ""
+		}
+		return strconv.Itoa(v.depth)
+	}},
+}
+
+// parseSortOrder looks up a sort order by name, falling back to by name
+func parseSortOrder(name string) fileSortOrder {
+	for order, orderName := range sortOrderNames {
+		if orderName == name {
+			return fileSortOrder(order)
+		}
+	}
+	return sortByName
+}
+
+// columnsText renders the chosen columns of a file, each right-aligned to its width
+func (state *appState) columnsText(file string) string {
+	validation, ok := state.validations[file]
+	var text strings.Builder
+	for _, column := range fileColumns {
+		if !state.showsColumn(column.name) {
+			continue
+		}
+		value := ""
+		if ok {
+			value = column.value(validation)
+		}
+		fmt.Fprintf(&text, " %*s", column.width, tview.Escape(value))
+	}
+	if text.Len() == 0 {
+		return ""
+	}
+	return "[gray]" + text.String() + "[-]"
+}
+
+// cycleSortOrder moves to the next sort order of the file list and saves it
+func (state *appState) cycleSortOrder() {
+	if state.leftRoot != "" {
+		state.debugView.SetText("Pairs of compared directories are always sorted by path.")
+		return
+	}
+	state.sortOrder = (state.sortOrder + 1) % fileSortOrder(len(sortOrderNames))
+	state.refreshFileList()
+
+	name := sortOrderNames[state.sortOrder]
+	if err := updateSettings(func(s *settings) { s.FileList.Sort = name }); err != nil {
+		errorLogger.Printf("Failed to save sort order: %v", err)
+		state.debugView.SetText("[red]Sorted by " + name + " but failed to save it. Check error log for details.[-]")
+		return
+	}
+	state.debugView.SetText("Files sorted by " + name + ". Press s for the next order.")
+}
+
+// lessFiles orders the files of a directory in the file list by the sort
+// order, then by name. Largest and newest files come first; files whose
+// size or modification time is not known yet come last.
+func (state *appState) lessFiles(a, b string) bool {
+	validationA, knownA := state.validations[a]
+	validationB, knownB := state.validations[b]
+	switch state.sortOrder {
+	case sortBySize:
+		knownA, knownB = knownA && !validationA.modTime.IsZero(), knownB && !validationB.modTime.IsZero()
+		if knownA != knownB {
+			return knownA
+		}
+		if validationA.size != validationB.size {
+			return validationA.size > validationB.size
+		}
+	case sortByModTime:
+		knownA, knownB = knownA && !validationA.modTime.IsZero(), knownB && !validationB.modTime.IsZero()
+		if knownA != knownB {
+			return knownA
+		}
+		if !validationA.modTime.Equal(validationB.modTime) {
+			return validationA.modTime.After(validationB.modTime)
+		}
+	case sortByStatus:
+		if statusA, statusB := state.fileStatus(a), state.fileStatus(b); statusA != statusB {
+			return statusA < statusB
+		}
+	}
+	return a < b
+}
+
+// showColumnsForm lets the columns of the file list be chosen and saves the choice
+func (state *appState) showColumnsForm() {
+	if state.leftRoot != "" {
+		state.debugView.SetText("The file list has no columns when comparing directories.")
+		return
+	}
+
+	form := tview.NewForm()
+	for _, column := range fileColumns {
+		form.AddCheckbox(column.label, state.showsColumn(column.name), nil)
+	}
+	form.AddButton("Save", func() {
+		var columns []string
+		for i, column := range fileColumns {
+			if form.GetFormItem(i).(*tview.Checkbox).IsChecked() {
+				columns = append(columns, column.name)
+			}
+		}
+		state.columns = columns
+		state.returnToMain()
+		state.refreshFileList()
+		if err := updateSettings(func(s *settings) { s.FileList.Columns = columns }); err != nil {
+			errorLogger.Printf("Failed to save columns: %v", err)
+			state.debugView.SetText("[red]Failed to save the columns. Check error log for details.[-]")
+		}
+	}).
+		AddButton("Cancel", state.returnToMain).
+		SetCancelFunc(state.returnToMain)
+	form.SetBorder(true).SetTitle("File list columns")
+
+	state.app.SetRoot(form, true).SetFocus(form)
+}
+
+// showsColumn reports whether the file list shows the named column
+func (state *appState) showsColumn(name string) bool {
+	for _, column := range state.columns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
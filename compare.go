@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Export formats offered for a comparison, in the order shown in the form
+const (
+	exportJSONPatch = iota
+	exportMergePatch
+	exportUnifiedDiff
+)
+
+var exportFormats = []string{"JSON Patch (RFC 6902)", "Merge Patch (RFC 7386)", "Unified diff"}
+
+// comparison holds the two documents shown in the compare view and the
+// structural differences between them.
+type comparison struct {
+	leftPath, rightPath string
+	left, right         interface{}
+	options             diffOptions
+	ops                 []diffOp
+}
+
+// defaultExportPath suggests a file name for an exported comparison
+func defaultExportPath(cmp *comparison, format int) string {
+	name := func(path string) string {
+		base := filepath.Base(path)
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	stem := name(cmp.leftPath) + "-" + name(cmp.rightPath)
+	switch format {
+	case exportMergePatch:
+		return stem + ".merge.json"
+	case exportUnifiedDiff:
+		return stem + ".diff"
+	default:
+		return stem + ".patch.json"
+	}
+}
+
+// exportComparison renders the comparison in the given format and writes it to path
+func exportComparison(cmp *comparison, format int, path string) error {
+	var data []byte
+	var err error
+
+	switch format {
+	case exportJSONPatch:
+		data, err = jsonPatch(cmp.ops)
+	case exportMergePatch:
+		data, err = json.MarshalIndent(mergePatch(nil, cmp.left, cmp.right, cmp.options), "", "  ")
+	case exportUnifiedDiff:
+		var leftText, rightText []byte
+		if leftText, err = json.MarshalIndent(cmp.left, "", "  "); err != nil {
+			break
+		}
+		if rightText, err = json.MarshalIndent(cmp.right, "", "  "); err != nil {
+			break
+		}
+		data = []byte(unifiedDiff("a/"+cmp.leftPath, "b/"+cmp.rightPath, string(leftText), string(rightText)))
+	default:
+		return fmt.Errorf("unknown export format %d", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render comparison: %w", err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// showComparePane displays a document in the second content pane, opening it if needed
+func (state *appState) showComparePane(title string, document formattedDocument) {
+	if state.secondFileContent == nil {
+		state.secondFileContent = tview.NewTextView().SetDynamicColors(true).SetRegions(true).SetWrap(true).SetScrollable(true)
+		state.secondFileContent.SetBorder(true).SetBorderColor(tcell.ColorGray)
+	}
+
+	state.panes[comparePane] = contentPane{view: state.secondFileContent, document: document}
+	state.secondFileContent.SetTitle(title)
+	state.compareFile = ""
+
+	if !state.secondFileVisible {
+		state.mainFlex.AddItem(state.secondFileContent, 0, 2, false)
+		state.secondFileVisible = true
+	}
+	if err := state.performSearch(); err != nil {
+		errorLogger.Printf("Search for %q failed: %v", state.searchString, err)
+	}
+}
+
+func (state *appState) showExportForm() {
+	if state.comparison == nil {
+		state.debugView.SetText("[red]Open the compare view (c) before exporting.[-]")
+		return
+	}
+	cmp := state.comparison
+
+	form := tview.NewForm()
+	pathField := tview.NewInputField().SetLabel("File").SetText(defaultExportPath(cmp, exportJSONPatch)).SetFieldWidth(50)
+	formatField := tview.NewDropDown().SetLabel("Format").SetOptions(exportFormats, func(_ string, index int) {
+		pathField.SetText(defaultExportPath(cmp, index))
+	}).SetCurrentOption(exportJSONPatch)
+
+	form.AddFormItem(formatField).
+		AddFormItem(pathField).
+		AddButton("Save", func() {
+			format, _ := formatField.GetCurrentOption()
+			path := pathField.GetText()
+			state.returnToMain()
+			if err := exportComparison(cmp, format, path); err != nil {
+				errorLogger.Printf("Failed to export comparison: %v", err)
+				state.debugView.SetText("[red]Failed to export comparison. Check error log for details.[-]")
+				return
+			}
+			infoLogger.Printf("Exported comparison of %s and %s to %s", cmp.leftPath, cmp.rightPath, path)
+			state.debugView.SetText(fmt.Sprintf("Exported %s to %s", exportFormats[format], path))
+		}).
+		AddButton("Cancel", state.returnToMain).
+		SetCancelFunc(state.returnToMain)
+	form.SetBorder(true).SetTitle(fmt.Sprintf("Export %s → %s", cmp.leftPath, cmp.rightPath))
+
+	state.app.SetRoot(form, true).SetFocus(form)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// settings are the preferences kept in config.json in the config directory
+type settings struct {
+	Discovery discoveryOptions `json:"discovery"`
+	FileList  fileListSettings `json:"file_list"`
+	History   historySettings  `json:"history"`
+}
+
+// fileListSettings are the columns and order of the file list
+type fileListSettings struct {
+	Columns []string `json:"columns"` // names of fileColumns to show
+	Sort    string   `json:"sort"`    // name of a fileSortOrder
+}
+
+// historySettings are how many snapshots of each open file are kept and
+// whether they are saved across sessions
+type historySettings struct {
+	Limit int  `json:"limit"` // 0 keeps no snapshots
+	Save  bool `json:"save"`  // append snapshots to the snapshots directory
+}
+
+// configDir returns the directory holding tjv's settings and history,
+// creating it if needed.
+func configDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	dir := filepath.Join(base, "tjv")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// loadSettings reads config.json from the config directory. Settings missing
+// from the file, or a missing file, keep their defaults.
+func loadSettings() (settings, error) {
+	loaded := settings{
+		Discovery: defaultDiscoveryOptions(),
+		FileList:  fileListSettings{Columns: []string{"size"}, Sort: "name"},
+		History:   historySettings{Limit: 20},
+	}
+	dir, err := configDir()
+	if err != nil {
+		return loaded, err
+	}
+	path := filepath.Join(dir, "config.json")
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return loaded, nil
+	}
+	if err != nil {
+		return loaded, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(content, &loaded); err != nil {
+		return loaded, fmt.Errorf("invalid settings in %s: %w", path, err)
+	}
+	return loaded, nil
+}
+
+// updateSettings applies change to the settings in config.json and writes
+// them back. Only what change touches is updated, so options given on the
+// command line are not saved.
+func updateSettings(change func(*settings)) error {
+	loaded, err := loadSettings()
+	if err != nil {
+		return err
+	}
+	change(&loaded)
+
+	content, err := json.MarshalIndent(loaded, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "config.json"), append(content, '\n'))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// finishWalk reports how the walk for JSON files ended
+func (state *appState) finishWalk(err error) {
+	switch {
+	case err != nil:
+		errorLogger.Printf("Failed to load JSON files: %v", err)
+		state.debugView.SetText("[red]Stopped looking for JSON files. Check error log for details.[-]")
+	case len(state.walkFailures) > 0:
+		infoLogger.Printf("JSON files loaded, %d paths skipped", len(state.walkFailures))
+		state.debugView.SetText(fmt.Sprintf("[yellow]%d paths could not be read and were skipped. Press E for details.[-]", len(state.walkFailures)))
+	default:
+		infoLogger.Println("JSON files loaded successfully")
+		state.debugView.SetText("Select a file to view its content.")
+	}
+}
+
+// showDiagnostics lists the paths the last walk skipped and the files that
+// failed validation, with the reasons
+func (state *appState) showDiagnostics() {
+	view := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	view.SetBorder(true)
+
+	var text strings.Builder
+	for _, failure := range state.walkFailures {
+		fmt.Fprintf(&text, "[yellow]%s[-]\n  %s\n", tview.Escape(failure.path), tview.Escape(failure.err.Error()))
+	}
+	if len(state.walkFailures) == 0 {
+		text.WriteString("No paths were skipped.\n")
+	}
+
+	// Files that were listed but failed background validation
+	var failed []string
+	for _, file := range state.allFiles {
+		if validation, ok := state.validations[file]; ok && validation.err != nil {
+			failed = append(failed, file)
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(&text, "\n[green]%d files failed validation:[-]\n", len(failed))
+	}
+	for _, file := range failed {
+		validation := state.validations[file]
+		fmt.Fprintf(&text, "%s %s\n  %s\n", statusBadges[validation.status], tview.Escape(file), tview.Escape(validation.err.Error()))
+	}
+	view.SetText(text.String())
+
+	title := fmt.Sprintf("Diagnostics: %d paths skipped", len(state.walkFailures))
+	if state.walking {
+		title += " so far, still scanning"
+	}
+	view.SetTitle(title + " (Esc: close)")
+
+	view.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || (event.Key() == tcell.KeyRune && (event.Rune() == 'q' || event.Rune() == 'E')) {
+			state.returnToMain()
+			return nil
+		}
+		return event
+	})
+	state.app.SetRoot(view, true).SetFocus(view)
+}
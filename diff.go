@@ -0,0 +1,506 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath identifies a value inside a document. Elements are object keys
+// (string) or array indices (int).
+type jsonPath []interface{}
+
+// diffOptions controls how two documents are compared.
+type diffOptions struct {
+	ignore     []pathPattern
+	arrayMatch string // "index", "lcs" or "key"
+	arrayKey   string // field used to pair objects when arrayMatch is "key"
+}
+
+// diffOp is a single structural difference between two documents, expressed
+// the way RFC 6902 would apply it to the left document.
+type diffOp struct {
+	op       string // "add", "remove" or "replace"
+	path     jsonPath
+	oldValue interface{}
+	newValue interface{}
+}
+
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+// edit is one step of an edit script produced by myersDiff. left and right
+// are indices into the two sequences; the one that does not apply is -1.
+type edit struct {
+	kind        editKind
+	left, right int
+}
+
+// patchOperation is the wire form of an RFC 6902 operation.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func diffArrays(path jsonPath, left, right []interface{}, options diffOptions) []diffOp {
+	var equal func(i, j int) bool
+	switch options.arrayMatch {
+	case "lcs":
+		equal = func(i, j int) bool { return reflect.DeepEqual(left[i], right[j]) }
+	case "key":
+		equal = func(i, j int) bool {
+			l, lok := left[i].(map[string]interface{})
+			r, rok := right[j].(map[string]interface{})
+			if !lok || !rok {
+				return reflect.DeepEqual(left[i], right[j])
+			}
+			lkey, lhas := l[options.arrayKey]
+			rkey, rhas := r[options.arrayKey]
+			return lhas && rhas && reflect.DeepEqual(lkey, rkey)
+		}
+	default:
+		return diffArraysByIndex(path, left, right, options)
+	}
+
+	// Apply the edit script in order, tracking where each element ends up
+	// so that the generated paths stay valid for sequential application.
+	var ops []diffOp
+	index := 0
+	for _, e := range myersDiff(len(left), len(right), equal) {
+		switch e.kind {
+		case editEqual:
+			ops = append(ops, diffValues(path.child(index), left[e.left], right[e.right], options)...)
+			index++
+		case editDelete:
+			if options.ignored(path.child(index)) {
+				index++ // An ignored element is left where it is
+				continue
+			}
+			ops = append(ops, diffOp{op: "remove", path: path.child(index), oldValue: left[e.left]})
+		case editInsert:
+			if options.ignored(path.child(index)) {
+				continue // Nothing is added, so nothing moves
+			}
+			ops = append(ops, diffOp{op: "add", path: path.child(index), newValue: right[e.right]})
+			index++
+		}
+	}
+	return ops
+}
+
+func diffArraysByIndex(path jsonPath, left, right []interface{}, options diffOptions) []diffOp {
+	var ops []diffOp
+	common := len(left)
+	if len(right) < common {
+		common = len(right)
+	}
+	for i := 0; i < common; i++ {
+		ops = append(ops, diffValues(path.child(i), left[i], right[i], options)...)
+	}
+	// Elements after a skipped one move up so that each add stays in bounds
+	index := common
+	for i := common; i < len(right); i++ {
+		if !options.ignored(path.child(i)) {
+			ops = append(ops, diffOp{op: "add", path: path.child(index), newValue: right[i]})
+			index++
+		}
+	}
+	// Remove from the end so that each index is still valid when applied in order
+	for i := len(left) - 1; i >= common; i-- {
+		if !options.ignored(path.child(i)) {
+			ops = append(ops, diffOp{op: "remove", path: path.child(i), oldValue: left[i]})
+		}
+	}
+	return ops
+}
+
+func diffObjects(path jsonPath, left, right map[string]interface{}, options diffOptions) []diffOp {
+	var ops []diffOp
+	for _, key := range unionKeys(left, right) {
+		if options.ignored(path.child(key)) {
+			continue
+		}
+		leftValue, inLeft := left[key]
+		rightValue, inRight := right[key]
+		switch {
+		case !inRight:
+			ops = append(ops, diffOp{op: "remove", path: path.child(key), oldValue: leftValue})
+		case !inLeft:
+			ops = append(ops, diffOp{op: "add", path: path.child(key), newValue: rightValue})
+		default:
+			ops = append(ops, diffValues(path.child(key), leftValue, rightValue, options)...)
+		}
+	}
+	return ops
+}
+
+// diffValues returns the operations that turn left into right. Objects are
+// compared key by key and arrays as described by options.arrayMatch.
+func diffValues(path jsonPath, left, right interface{}, options diffOptions) []diffOp {
+	if options.ignored(path) {
+		return nil
+	}
+	switch l := left.(type) {
+	case map[string]interface{}:
+		if r, ok := right.(map[string]interface{}); ok {
+			return diffObjects(path, l, r, options)
+		}
+	case []interface{}:
+		if r, ok := right.([]interface{}); ok {
+			return diffArrays(path, l, r, options)
+		}
+	}
+	if reflect.DeepEqual(left, right) {
+		return nil
+	}
+	return []diffOp{{op: "replace", path: path, oldValue: left, newValue: right}}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func isPlainKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if r != '_' && r != '-' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonPatch converts diff operations to an RFC 6902 JSON Patch document.
+func jsonPatch(ops []diffOp) ([]byte, error) {
+	patch := make([]patchOperation, 0, len(ops))
+	for _, op := range ops {
+		operation := patchOperation{Op: op.op, Path: op.path.pointer()}
+		if op.op != "remove" {
+			value, err := json.Marshal(op.newValue)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode value at %s: %w", operation.Path, err)
+			}
+			operation.Value = value
+		}
+		patch = append(patch, operation)
+	}
+	return json.MarshalIndent(patch, "", "  ")
+}
+
+// mergePatch builds an RFC 7386 merge patch that turns left into right,
+// leaving out paths ignored by options. Values that are replaced whole, such
+// as arrays, are copied without their ignored members.
+func mergePatch(path jsonPath, left, right interface{}, options diffOptions) interface{} {
+	l, leftIsObject := left.(map[string]interface{})
+	r, rightIsObject := right.(map[string]interface{})
+	if !leftIsObject || !rightIsObject {
+		return withoutIgnored(path, right, options)
+	}
+
+	patch := map[string]interface{}{}
+	for _, key := range unionKeys(l, r) {
+		if options.ignored(path.child(key)) {
+			continue
+		}
+		leftValue, inLeft := l[key]
+		rightValue, inRight := r[key]
+		switch {
+		case !inRight:
+			patch[key] = nil
+		case !inLeft:
+			patch[key] = withoutIgnored(path.child(key), rightValue, options)
+		case !reflect.DeepEqual(leftValue, rightValue):
+			patch[key] = mergePatch(path.child(key), leftValue, rightValue, options)
+		}
+	}
+	return patch
+}
+
+// myersDiff computes the shortest edit script between two sequences of
+// length n and m using Myers' O(ND) algorithm.
+func myersDiff(n, m int, equal func(i, j int) bool) []edit {
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && equal(x, y) {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the path
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{kind: editEqual, left: x - 1, right: y - 1})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{kind: editInsert, left: -1, right: y - 1})
+			} else {
+				edits = append(edits, edit{kind: editDelete, left: x - 1, right: -1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// newDiffOptions validates the ignore patterns and array matching mode given
+// on the command line. arrayMatch is "index", "lcs" or "key=FIELD".
+func newDiffOptions(ignore []string, arrayMatch string) (diffOptions, error) {
+	var options diffOptions
+	for _, pattern := range ignore {
+		parsed, err := parsePathPattern(pattern)
+		if err != nil {
+			return options, err
+		}
+		options.ignore = append(options.ignore, parsed)
+	}
+
+	switch {
+	case arrayMatch == "" || arrayMatch == "index":
+		options.arrayMatch = "index"
+	case arrayMatch == "lcs":
+		options.arrayMatch = "lcs"
+	case strings.HasPrefix(arrayMatch, "key=") && len(arrayMatch) > len("key="):
+		options.arrayMatch = "key"
+		options.arrayKey = strings.TrimPrefix(arrayMatch, "key=")
+	default:
+		return options, fmt.Errorf("unknown array matching mode %q (want index, lcs or key=FIELD)", arrayMatch)
+	}
+	return options, nil
+}
+
+// splitLines splits text into lines without their newlines. Empty text has
+// no lines rather than a single empty one.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// unifiedDiff returns a unified diff of two texts with three lines of context.
+func unifiedDiff(leftName, rightName, leftText, rightText string) string {
+	const context = 3
+
+	leftLines, rightLines := splitLines(leftText), splitLines(rightText)
+	edits := myersDiff(len(leftLines), len(rightLines), func(i, j int) bool {
+		return leftLines[i] == rightLines[j]
+	})
+
+	var out strings.Builder
+	for start := 0; start < len(edits); {
+		// Find the next change
+		for start < len(edits) && edits[start].kind == editEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", leftName, rightName)
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		first := start - context
+		if first < 0 {
+			first = 0
+		}
+		end := start
+		for end < len(edits) {
+			if edits[end].kind != editEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].kind == editEqual {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		last := end + context
+		if last > len(edits) {
+			last = len(edits)
+		}
+
+		leftStart, rightStart, leftCount, rightCount := 0, 0, 0, 0
+		for i := 0; i < first; i++ {
+			if edits[i].kind != editInsert {
+				leftStart++
+			}
+			if edits[i].kind != editDelete {
+				rightStart++
+			}
+		}
+		var body strings.Builder
+		for _, e := range edits[first:last] {
+			switch e.kind {
+			case editEqual:
+				body.WriteString(" " + leftLines[e.left] + "\n")
+				leftCount++
+				rightCount++
+			case editDelete:
+				body.WriteString("-" + leftLines[e.left] + "\n")
+				leftCount++
+			case editInsert:
+				body.WriteString("+" + rightLines[e.right] + "\n")
+				rightCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(leftStart, leftCount), hunkRange(rightStart, rightCount))
+		out.WriteString(body.String())
+
+		start = last
+	}
+	return out.String()
+}
+
+func unionKeys(left, right map[string]interface{}) []string {
+	keys := make([]string, 0, len(left)+len(right))
+	for key := range left {
+		keys = append(keys, key)
+	}
+	for key := range right {
+		if _, ok := left[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// withoutIgnored returns a copy of value at path with the object members and
+// array elements ignored by options left out.
+func withoutIgnored(path jsonPath, value interface{}, options diffOptions) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		kept := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if !options.ignored(path.child(key)) {
+				kept[key] = withoutIgnored(path.child(key), child, options)
+			}
+		}
+		return kept
+	case []interface{}:
+		kept := make([]interface{}, 0, len(v))
+		for i, child := range v {
+			if !options.ignored(path.child(i)) {
+				kept = append(kept, withoutIgnored(path.child(i), child, options))
+			}
+		}
+		return kept
+	}
+	return value
+}
+
+func (options diffOptions) ignored(path jsonPath) bool {
+	for _, pattern := range options.ignore {
+		if pattern.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p jsonPath) child(token interface{}) jsonPath {
+	child := make(jsonPath, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, token)
+}
+
+// pointer returns the path as an RFC 6901 JSON Pointer.
+func (p jsonPath) pointer() string {
+	var sb strings.Builder
+	for _, token := range p {
+		sb.WriteByte('/')
+		switch t := token.(type) {
+		case int:
+			sb.WriteString(strconv.Itoa(t))
+		case string:
+			sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+		}
+	}
+	return sb.String()
+}
+
+// String returns the path in the dotted form shown in the UI, e.g. .spec.containers[0].image
+func (p jsonPath) String() string {
+	if len(p) == 0 {
+		return "."
+	}
+	var sb strings.Builder
+	for _, token := range p {
+		switch t := token.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(t) + "]")
+		case string:
+			if isPlainKey(t) {
+				sb.WriteString("." + t)
+			} else {
+				sb.WriteString("[" + strconv.Quote(t) + "]")
+			}
+		}
+	}
+	return sb.String()
+}
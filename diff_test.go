@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMyersDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		want        string // one letter per edit: = equal, - delete, + insert
+	}{
+		{"both empty", "", "", ""},
+		{"insert all", "", "abc", "+++"},
+		{"delete all", "abc", "", "---"},
+		{"equal", "abc", "abc", "==="},
+		{"replace middle", "abc", "axc", "=-+="},
+		{"insert middle", "ac", "abc", "=+="},
+		{"classic", "abcabba", "cbabac", "--=+==-=+"},
+	}
+	symbols := map[editKind]string{editEqual: "=", editDelete: "-", editInsert: "+"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			left, right := []rune(test.left), []rune(test.right)
+			edits := myersDiff(len(left), len(right), func(i, j int) bool { return left[i] == right[j] })
+
+			var got strings.Builder
+			var rebuilt []rune
+			for _, e := range edits {
+				got.WriteString(symbols[e.kind])
+				switch e.kind {
+				case editEqual:
+					if left[e.left] != right[e.right] {
+						t.Errorf("edit %+v pairs unequal elements", e)
+					}
+					rebuilt = append(rebuilt, left[e.left])
+				case editInsert:
+					rebuilt = append(rebuilt, right[e.right])
+				}
+			}
+			if string(rebuilt) != test.right {
+				t.Errorf("applying the edits gives %q, want %q", string(rebuilt), test.right)
+			}
+			// Edit scripts of the same length are equally valid; only the length is fixed
+			if changes, want := strings.Count(got.String(), "-")+strings.Count(got.String(), "+"), strings.Count(test.want, "-")+strings.Count(test.want, "+"); changes != want {
+				t.Errorf("edit script %s has %d changes, want %d", got.String(), changes, want)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		want        string
+	}{
+		{"no changes", `{"a":1}`, `{"a":1}`, `[]`},
+		{"replace", `{"a":1}`, `{"a":2}`, `[{"op":"replace","path":"/a","value":2}]`},
+		{"add and remove", `{"a":1}`, `{"b":true}`, `[{"op":"remove","path":"/a"},{"op":"add","path":"/b","value":true}]`},
+		{"escaped key", `{"a/b":1,"c~d":1}`, `{"a/b":2,"c~d":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`},
+		{"array grows", `[1]`, `[1,2]`, `[{"op":"add","path":"/1","value":2}]`},
+		{"array shrinks from the end", `[1,2,3]`, `[1]`, `[{"op":"remove","path":"/2"},{"op":"remove","path":"/1"}]`},
+		{"null value", `{"a":1}`, `{"a":null}`, `[{"op":"replace","path":"/a","value":null}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := jsonPatch(diffValues(nil, decode(t, test.left), decode(t, test.right), diffOptions{arrayMatch: "index"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := decode(t, string(patch)), decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %s, want %s", patch, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffArraysIgnoredElements(t *testing.T) {
+	tests := []struct {
+		name        string
+		arrayMatch  string
+		left, right string
+		want        string // left after the patch is applied
+	}{
+		{"ignored delete stays", "lcs", `{"l":["a","tmp","b"]}`, `{"l":["a","b","c"]}`, `{"l":["a","tmp","b","c"]}`},
+		{"ignored insert is skipped", "lcs", `{"l":["a","b"]}`, `{"l":["a","new","b","c"]}`, `{"l":["a","b","c"]}`},
+		{"key match", "key=id", `{"l":[{"id":1},{"id":2},{"id":3}]}`, `{"l":[{"id":1},{"id":3},{"id":4}]}`,
+			`{"l":[{"id":1},{"id":2},{"id":3},{"id":4}]}`},
+		{"index match", "index", `{"l":["a"]}`, `{"l":["a","new","c"]}`, `{"l":["a","c"]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options, err := newDiffOptions([]string{"l[1]"}, test.arrayMatch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := decode(t, test.left)
+			for _, op := range diffValues(nil, got, decode(t, test.right), options) {
+				if got, err = applyOp(got, op.path, op); err != nil {
+					t.Fatalf("%s %s: %v", op.op, op.path.pointer(), err)
+				}
+			}
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		options     diffOptions
+		want        string
+	}{
+		{"no changes", `{"a":1}`, `{"a":1}`, diffOptions{}, `{}`},
+		{"removed key becomes null", `{"a":1,"b":2}`, `{"a":1}`, diffOptions{}, `{"b":null}`},
+		{"nested change", `{"a":{"b":1,"c":2}}`, `{"a":{"b":1,"c":3}}`, diffOptions{}, `{"a":{"c":3}}`},
+		{"arrays are replaced", `{"a":[1,2]}`, `{"a":[1]}`, diffOptions{}, `{"a":[1]}`},
+		{"non-object right", `{"a":1}`, `[1]`, diffOptions{}, `[1]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mergePatch(nil, decode(t, test.left), decode(t, test.right), test.options)
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergePatchIgnoredPaths(t *testing.T) {
+	ignored, err := newDiffOptions([]string{"**.time"}, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		left, right string
+		want        string
+	}{
+		{"changed member", `{"a":{"time":1,"b":1}}`, `{"a":{"time":2,"b":2}}`, `{"a":{"b":2}}`},
+		{"inside a replaced array", `{"a":[{"time":1}]}`, `{"a":[{"time":2,"b":1}]}`, `{"a":[{"b":1}]}`},
+		{"inside an added value", `{}`, `{"a":{"time":2,"b":{"time":3}}}`, `{"a":{"b":{}}}`},
+		{"inside a replaced root", `{}`, `[{"time":2}]`, `[{}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mergePatch(nil, decode(t, test.left), decode(t, test.right), ignored)
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		want        string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"both empty", "", "", ""},
+		{"from empty", "", "a\nb\n", "--- a/x\n+++ b/x\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"to empty", "a\n", "", "--- a/x\n+++ b/x\n@@ -1 +0,0 @@\n-a\n"},
+		{"change with context", "1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nx\n6\n7\n8\n",
+			"--- a/x\n+++ b/x\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+x\n 6\n 7\n 8\n"},
+		{"distant changes make two hunks", "a\n1\n2\n3\n4\n5\n6\n7\nb\n", "A\n1\n2\n3\n4\n5\n6\n7\nB\n",
+			"--- a/x\n+++ b/x\n@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-b\n+B\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := unifiedDiff("a/x", "b/x", test.left, test.right); got != test.want {
+				t.Errorf("got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+// decode parses a JSON literal of a test table
+func decode(t *testing.T, text string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		t.Fatalf("invalid JSON %q: %v", text, err)
+	}
+	return value
+}
+
+// applyOp applies one diff operation at path below value, failing the way
+// an RFC 6902 patch would when the path does not exist
+func applyOp(value interface{}, path jsonPath, op diffOp) (interface{}, error) {
+	if len(path) == 0 {
+		return op.newValue, nil
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		key, _ := path[0].(string)
+		if len(path) > 1 {
+			child, err := applyOp(v[key], path[1:], op)
+			v[key] = child
+			return v, err
+		}
+		if op.op == "remove" {
+			delete(v, key)
+		} else {
+			v[key] = op.newValue
+		}
+		return v, nil
+	case []interface{}:
+		i, _ := path[0].(int)
+		if i < 0 || i > len(v) || (i == len(v) && (len(path) > 1 || op.op != "add")) {
+			return v, fmt.Errorf("index %d out of range for %d elements", i, len(v))
+		}
+		switch {
+		case len(path) > 1:
+			child, err := applyOp(v[i], path[1:], op)
+			v[i] = child
+			return v, err
+		case op.op == "add":
+			return append(v[:i], append([]interface{}{op.newValue}, v[i:]...)...), nil
+		case op.op == "remove":
+			return append(v[:i], v[i+1:]...), nil
+		default:
+			v[i] = op.newValue
+			return v, nil
+		}
+	}
+	return value, fmt.Errorf("cannot %s below a scalar", op.op)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+type pairStatus int
+
+const (
+	pairIdentical pairStatus = iota
+	pairDiffers
+	pairOnlyLeft
+	pairOnlyRight
+)
+
+// Markers shown in front of each pair in the file list
+var pairMarkers = map[pairStatus]string{
+	pairIdentical: "=",
+	pairDiffers:   "≠",
+	pairOnlyLeft:  "◀",
+	pairOnlyRight: "▶",
+}
+
+// filePair is a JSON file present under at least one of two compared roots
+type filePair struct {
+	relPath string
+	status  pairStatus
+}
+
+// comparePairFiles reports whether two files hold the same document. Files
+// that cannot be parsed are compared byte for byte.
+func comparePairFiles(leftFile, rightFile string, options diffOptions) pairStatus {
+	left, leftErr := readJSONFile(leftFile)
+	right, rightErr := readJSONFile(rightFile)
+	if leftErr == nil && rightErr == nil {
+		if len(diffValues(nil, left, right, options)) == 0 {
+			return pairIdentical
+		}
+		return pairDiffers
+	}
+
+	leftContent, leftErr := readFileContent(leftFile)
+	rightContent, rightErr := readFileContent(rightFile)
+	if leftErr == nil && rightErr == nil && leftContent == rightContent {
+		return pairIdentical
+	}
+	return pairDiffers
+}
+
+// loadDirectoryPairs pairs the JSON files under two roots by relative path
+// and passes each pair to found in path order as soon as it is compared.
+// Paths of either root that could not be read are returned.
+func loadDirectoryPairs(ctx context.Context, leftRoot, rightRoot string, options diffOptions, discovery discoveryOptions, found func(pair filePair)) ([]walkFailure, error) {
+	var failures []walkFailure
+	relativeFiles := func(root string) (map[string]bool, error) {
+		files, failed, err := loadJSONFilesWithContext(ctx, root, discovery)
+		failures = append(failures, failed...)
+		if err != nil {
+			return nil, err
+		}
+		set := make(map[string]bool, len(files))
+		for _, file := range files {
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving %s: %w", file, err)
+			}
+			set[rel] = true
+		}
+		return set, nil
+	}
+
+	leftFiles, err := relativeFiles(leftRoot)
+	if err != nil {
+		return failures, err
+	}
+	rightFiles, err := relativeFiles(rightRoot)
+	if err != nil {
+		return failures, err
+	}
+
+	rels := make([]string, 0, len(leftFiles)+len(rightFiles))
+	for rel := range leftFiles {
+		rels = append(rels, rel)
+	}
+	for rel := range rightFiles {
+		if !leftFiles[rel] {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+
+	for _, rel := range rels {
+		if err := ctx.Err(); err != nil {
+			return failures, err
+		}
+		switch {
+		case !rightFiles[rel]:
+			found(filePair{relPath: rel, status: pairOnlyLeft})
+		case !leftFiles[rel]:
+			found(filePair{relPath: rel, status: pairOnlyRight})
+		default:
+			found(filePair{relPath: rel, status: comparePairFiles(filepath.Join(leftRoot, rel), filepath.Join(rightRoot, rel), options)})
+		}
+	}
+	return failures, nil
+}
+
+// finishPairs reports how comparing the two roots ended
+func (state *appState) finishPairs(err error) {
+	if err != nil {
+		errorLogger.Printf("Failed to compare directories: %v", err)
+		state.debugView.SetText("[red]Failed to compare directories. Check error log for details.[-]")
+		return
+	}
+
+	counts := map[pairStatus]int{}
+	for _, pair := range state.pairs {
+		counts[pair.status]++
+	}
+	infoLogger.Printf("Compared %s with %s: %d pairs", state.leftRoot, state.rightRoot, len(state.pairs))
+	summary := fmt.Sprintf("= %d identical, ≠ %d differ, ◀ %d only left, ▶ %d only right",
+		counts[pairIdentical], counts[pairDiffers], counts[pairOnlyLeft], counts[pairOnlyRight])
+	if len(state.walkFailures) > 0 {
+		summary += fmt.Sprintf(" [yellow](%d paths skipped, E for details)[-]", len(state.walkFailures))
+	}
+	state.debugView.SetText(summary)
+}
+
+// openPair shows both sides of a pair and their structural diff
+func (state *appState) openPair(pair filePair, fileIndex int) {
+	leftFile := filepath.Join(state.leftRoot, pair.relPath)
+	rightFile := filepath.Join(state.rightRoot, pair.relPath)
+	if state.blockedByEdits() {
+		return
+	}
+
+	state.activeFileIndex = fileIndex
+	updateActiveFileHighlight(state.fileList, fileIndex)
+
+	switch pair.status {
+	case pairOnlyLeft, pairOnlyRight:
+		file, root := leftFile, state.leftRoot
+		if pair.status == pairOnlyRight {
+			file, root = rightFile, state.rightRoot
+		}
+		state.runLoad(loadMain, pair.relPath, func(ctx context.Context, job *loadJob) (func(), error) {
+			value, err := job.readFile(ctx, file)
+			if err != nil {
+				return nil, err
+			}
+			job.phase.Store(loadFormatting)
+			document := newFormattedDocument(value, nil)
+
+			return func() {
+				if state.secondFileVisible {
+					state.mainFlex.RemoveItem(state.secondFileContent)
+					state.secondFileVisible = false
+				}
+				state.setContentDocument(file, document)
+				state.comparison = nil
+				state.debugView.SetText("Only in " + root)
+			}, nil
+		})
+		return
+	}
+
+	options := state.diffOptions
+	state.runLoad(loadMain, pair.relPath, func(ctx context.Context, job *loadJob) (func(), error) {
+		left, err := job.readFile(ctx, leftFile)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadReading)
+		job.read.Store(0)
+		right, err := job.readFile(ctx, rightFile)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadFormatting)
+		leftDocument, rightDocument := newFormattedDocument(left, nil), newFormattedDocument(right, nil)
+		job.phase.Store(loadComparing)
+		cmp := &comparison{
+			leftPath:  leftFile,
+			rightPath: rightFile,
+			left:      left,
+			right:     right,
+			options:   options,
+			ops:       diffValues(nil, left, right, options),
+		}
+
+		return func() {
+			state.setContentDocument(leftFile, leftDocument)
+			state.showComparePane(rightFile, rightDocument)
+			state.compareFile = rightFile
+			state.comparison = cmp
+			if len(cmp.ops) == 0 {
+				state.debugView.SetText("Documents are identical.")
+				return
+			}
+			state.debugView.SetText(fmt.Sprintf("%d differences. Press x to export.", len(cmp.ops)))
+		}, nil
+	})
+}
+
+// refreshPairList shows the pairs compared so far in the file list
+func (state *appState) refreshPairList() {
+	current := state.fileList.GetCurrentItem()
+	state.fileList.Clear()
+	state.actionFuncs = nil
+	state.files = nil
+
+	for i, pair := range state.pairs {
+		pair := pair // capture range variable
+		fileIndex := i
+		action := func() {
+			state.openPair(pair, fileIndex)
+		}
+		state.fileList.AddItem(pairMarkers[pair.status]+" "+tview.Escape(pair.relPath), "", 0, action)
+		state.actionFuncs = append(state.actionFuncs, action)
+	}
+	if current < len(state.pairs) {
+		state.fileList.SetCurrentItem(current)
+	}
+	updateActiveFileHighlight(state.fileList, state.activeFileIndex)
+
+	title := fmt.Sprintf("%s ↔ %s", state.leftRoot, state.rightRoot)
+	if state.walking {
+		title += fmt.Sprintf(" (%d, comparing…)", len(state.pairs))
+	}
+	state.fileList.SetTitle(title)
+}
+
+// reloadDirectoryPairs compares the two roots in the background and streams
+// their pairs into the file list, dropping the results of an earlier reload
+func (state *appState) reloadDirectoryPairs() {
+	if state.cancelWalk != nil {
+		state.cancelWalk()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	state.cancelWalk = cancel
+	state.walkGeneration++
+	generation := state.walkGeneration
+
+	state.pairs = nil
+	state.walkFailures = nil
+	state.activeFileIndex = -1
+	state.walking = true
+	state.refreshPairList()
+	state.debugView.SetText("Comparing " + tview.Escape(state.leftRoot) + " with " + tview.Escape(state.rightRoot) + "…")
+
+	leftRoot, rightRoot := state.leftRoot, state.rightRoot
+	options, discovery := state.diffOptions, state.discovery
+	go func() {
+		defer cancel()
+		var pairs []filePair
+		// flush hands the pairs compared so far to the UI unless a newer reload started
+		flush := func(done func()) {
+			found := pairs
+			pairs = nil
+			state.app.QueueUpdateDraw(func() {
+				if generation != state.walkGeneration {
+					return
+				}
+				state.pairs = append(state.pairs, found...)
+				if done != nil {
+					done()
+				}
+				state.refreshPairList()
+			})
+		}
+
+		lastFlush := time.Now()
+		failures, err := loadDirectoryPairs(ctx, leftRoot, rightRoot, options, discovery, func(pair filePair) {
+			pairs = append(pairs, pair)
+			if time.Since(lastFlush) >= walkFlushInterval {
+				flush(nil)
+				lastFlush = time.Now()
+			}
+		})
+		flush(func() {
+			state.walking = false
+			state.walkFailures = failures
+			state.finishPairs(err)
+		})
+	}()
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// discoveryOptions control which files the file list picks up
+type discoveryOptions struct {
+	Extensions []string `json:"extensions"`
+	Include    []string `json:"include"`   // if set, files must match one of these globs
+	Exclude    []string `json:"exclude"`   // files and directories matching these globs are skipped
+	MaxDepth   int      `json:"max_depth"` // directory levels below the root; 0 means unlimited
+	Hidden     bool     `json:"hidden"`    // include files and directories starting with a dot
+	Gitignore  bool     `json:"gitignore"` // skip what .gitignore files under the root ignore
+	// Descend into symlinked directories; each directory is visited once
+	FollowSymlinks bool `json:"follow_symlinks"`
+}
+
+// gitignoreRule is one pattern of a .gitignore file
+type gitignoreRule struct {
+	segments pathPattern
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// walkFailure is a path the walk skipped because it could not be read
+type walkFailure struct {
+	path string
+	err  error
+}
+
+// discovery walks a root directory applying discoveryOptions
+type discovery struct {
+	root    string
+	options discoveryOptions
+	ignores map[string][]gitignoreRule // rules of each directory, by path relative to root
+}
+
+func defaultDiscoveryOptions() discoveryOptions {
+	return discoveryOptions{
+		Extensions: []string{".json"},
+		Exclude:    []string{"node_modules", "vendor"},
+		Gitignore:  true,
+	}
+}
+
+// gunzip decompresses the content of source, which names it in errors
+func gunzip(source string, content []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data in %s: %w", source, err)
+	}
+	defer reader.Close()
+	if content, err = io.ReadAll(reader); err != nil {
+		return nil, fmt.Errorf("error decompressing %s: %w", source, err)
+	}
+	return content, nil
+}
+
+// hasDocumentExtension reports whether name ends in one of the extensions
+func hasDocumentExtension(name string, extensions []string) bool {
+	lower := strings.ToLower(name)
+	for _, extension := range extensions {
+		if strings.HasSuffix(lower, strings.ToLower(extension)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash separated path relative to the root against a
+// glob. Globs without a slash match the last element at any depth; "**"
+// matches any number of directories.
+func matchGlob(glob, rel string) bool {
+	glob = strings.TrimPrefix(glob, "./")
+	tokens := strings.Split(rel, "/")
+	if !strings.Contains(glob, "/") {
+		return matchSegments(pathPattern{glob}, tokens[len(tokens)-1:])
+	}
+	return matchSegments(pathPattern(strings.Split(strings.Trim(glob, "/"), "/")), tokens)
+}
+
+// parseDocument decodes file content by extension: .gz files are
+// decompressed first and JSON Lines files become an array of their lines.
+func parseDocument(filePath string, content []byte) (interface{}, error) {
+	name := strings.ToLower(filePath)
+	if strings.HasSuffix(name, ".gz") {
+		var err error
+		if content, err = gunzip(filePath, content); err != nil {
+			return nil, err
+		}
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	if strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".ndjson") {
+		values, err := parseLines(filePath, content)
+		if err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	return parseJSON(filePath, content)
+}
+
+// parseGitignore reads the rules of a .gitignore file
+func parseGitignore(content string) []gitignoreRule {
+	var rules []gitignoreRule
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule gitignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		// A slash anywhere but at the end ties the pattern to the .gitignore's directory
+		rule.anchored = strings.Contains(line, "/")
+		rule.segments = pathPattern(strings.Split(strings.TrimPrefix(line, "/"), "/"))
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// parseJSON decodes content holding a single JSON document
+func parseJSON(source string, content []byte) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON in %s: %w", source, err)
+	}
+	return value, nil
+}
+
+// parseLines decodes JSON Lines content into an array of its non-blank lines
+func parseLines(source string, content []byte) ([]interface{}, error) {
+	values := []interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), len(content)+1)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(line, &value); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d of %s: %w", lineNumber, source, err)
+		}
+		values = append(values, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", source, err)
+	}
+	return values, nil
+}
+
+// walkDocuments calls found for every document under root that the options
+// select, and for every directory it descends into. Paths that cannot be read
+// are passed to failed and skipped, so one bad directory or broken symlink
+// does not end the walk; only cancellation or an unreadable root does.
+func walkDocuments(ctx context.Context, root string, options discoveryOptions, found func(path string, dir bool), failed func(walkFailure)) error {
+	if _, err := os.Stat(root); err != nil {
+		return fmt.Errorf("error accessing %s: %w", root, err)
+	}
+	d := &discovery{root: root, options: options, ignores: map[string][]gitignoreRule{}}
+	visited := map[string]string{} // resolved directory → path it was first reached by
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if options.FollowSymlinks {
+			resolved, err := filepath.EvalSymlinks(dir)
+			if err == nil {
+				resolved, err = filepath.Abs(resolved)
+			}
+			if err != nil {
+				failed(walkFailure{dir, err})
+				return nil
+			}
+			if first, ok := visited[resolved]; ok {
+				if strings.HasPrefix(dir, first+string(filepath.Separator)) {
+					failed(walkFailure{dir, fmt.Errorf("symlink loop back to %s", first)})
+				} else {
+					failed(walkFailure{dir, fmt.Errorf("already listed as %s", first)})
+				}
+				return nil
+			}
+			visited[resolved] = dir
+		}
+
+		// ReadDir returns the entries it read before an error
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			failed(walkFailure{dir, err})
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				failed(walkFailure{path, err})
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+
+			isDir := entry.IsDir()
+			if entry.Type()&fs.ModeSymlink != 0 {
+				info, err := os.Stat(path)
+				if err != nil {
+					if !d.skip(rel, entry.Name(), false) {
+						failed(walkFailure{path, fmt.Errorf("broken symlink: %w", err)})
+					}
+					continue
+				}
+				if info.IsDir() && !options.FollowSymlinks {
+					continue
+				}
+				isDir = info.IsDir()
+			}
+
+			if d.skip(rel, entry.Name(), isDir) {
+				continue
+			}
+			if isDir {
+				found(path, true)
+				if err := walk(path); err != nil {
+					return err
+				}
+				continue
+			}
+			found(path, false)
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+// gitignored reports whether the .gitignore files of the directories above
+// rel ignore it. Later rules and deeper files take precedence.
+func (d *discovery) gitignored(rel string, isDir bool) bool {
+	tokens := strings.Split(rel, "/")
+	ignored := false
+	for depth := 0; depth < len(tokens); depth++ {
+		dir := strings.Join(tokens[:depth], "/")
+		if dir == "" {
+			dir = "."
+		}
+		for _, rule := range d.rules(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			relative := tokens[depth:]
+			var matched bool
+			if rule.anchored {
+				matched = matchSegments(rule.segments, relative)
+			} else {
+				matched = matchSegments(rule.segments, relative[len(relative)-1:])
+			}
+			if matched {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// rules loads the .gitignore of a directory relative to the root once
+func (d *discovery) rules(dir string) []gitignoreRule {
+	if rules, ok := d.ignores[dir]; ok {
+		return rules
+	}
+	var rules []gitignoreRule
+	if content, err := os.ReadFile(filepath.Join(d.root, filepath.FromSlash(dir), ".gitignore")); err == nil {
+		rules = parseGitignore(string(content))
+	}
+	d.ignores[dir] = rules
+	return rules
+}
+
+// selects reports whether a walk would reach the file or directory at rel,
+// a slash separated path relative to the root
+func (d *discovery) selects(rel string, isDir bool) bool {
+	tokens := strings.Split(rel, "/")
+	for i := 1; i < len(tokens); i++ {
+		if d.skip(strings.Join(tokens[:i], "/"), tokens[i-1], true) {
+			return false
+		}
+	}
+	return !d.skip(rel, tokens[len(tokens)-1], isDir)
+}
+
+// skip reports whether an entry found while walking is left out
+func (d *discovery) skip(rel string, name string, isDir bool) bool {
+	if !d.options.Hidden && strings.HasPrefix(name, ".") {
+		return true
+	}
+	// Files of a directory within reach are listed, so only directories count
+	if isDir && d.options.MaxDepth > 0 && strings.Count(rel, "/")+1 > d.options.MaxDepth {
+		return true
+	}
+	for _, glob := range d.options.Exclude {
+		if matchGlob(glob, rel) {
+			return true
+		}
+	}
+	if d.options.Gitignore && d.gitignored(rel, isDir) {
+		return true
+	}
+	if isDir {
+		return false
+	}
+
+	if !hasDocumentExtension(name, d.options.Extensions) {
+		return true
+	}
+	if len(d.options.Include) == 0 {
+		return false
+	}
+	for _, glob := range d.options.Include {
+		if matchGlob(glob, rel) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, rel string
+		want      bool
+	}{
+		{"node_modules", "node_modules", true},
+		{"node_modules", "web/node_modules", true},
+		{"*.json", "a/b/c.json", true},
+		{"configs/**/*.json", "configs/prod/eu/app.json", true},
+		{"configs/**/*.json", "configs/app.json", true},
+		{"configs/*.json", "configs/prod/app.json", false},
+		{"./configs/*.json", "configs/app.json", true},
+		{"/logs", "logs", true},
+		{"/logs", "a/logs", false},
+	}
+	for _, test := range tests {
+		if got := matchGlob(test.glob, test.rel); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", test.glob, test.rel, got, test.want)
+		}
+	}
+}
+
+func TestGitignored(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "# comment\n*.log.json\nbuild/\n/top.json\n!keep.log.json\ndocs/*.json\n")
+	write("sub/.gitignore", "local.json\n!/top.json\n")
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"app.log.json", false, true},
+		{"deep/er/app.log.json", false, true},
+		{"keep.log.json", false, false},
+		{"build", true, true},
+		{"build", false, false}, // build/ only matches directories
+		{"top.json", false, true},
+		{"sub/top.json", false, false}, // Anchored to the root .gitignore
+		{"docs/a.json", false, true},
+		{"docs/x/a.json", false, false},
+		{"sub/local.json", false, true},
+		{"local.json", false, false}, // Rules only apply below their directory
+		{"plain.json", false, false},
+	}
+	d := &discovery{root: root, options: defaultDiscoveryOptions(), ignores: map[string][]gitignoreRule{}}
+	for _, test := range tests {
+		if got := d.gitignored(test.rel, test.isDir); got != test.want {
+			t.Errorf("gitignored(%q, dir=%v) = %v, want %v", test.rel, test.isDir, got, test.want)
+		}
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          string
+		fails         bool
+	}{
+		{"a.json", `{"a":1}`, `{"a":1}`, false},
+		{"a.json", `{"a":`, "", true},
+		{"a.jsonl", "{\"a\":1}\n\n[2]\n", `[{"a":1},[2]]`, false},
+		{"a.NDJSON", "1\n2", `[1,2]`, false},
+		{"a.jsonl", "1\nnope\n", "", true},
+		{"a.jsonl", "", `[]`, false},
+	}
+	for _, test := range tests {
+		got, err := parseDocument(test.name, []byte(test.content))
+		if test.fails {
+			if err == nil {
+				t.Errorf("parseDocument(%q, %q) succeeded, want an error", test.name, test.content)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDocument(%q, %q): %v", test.name, test.content, err)
+			continue
+		}
+		if want := decode(t, test.want); !jsonEqual(got, want) {
+			t.Errorf("parseDocument(%q, %q) = %v, want %v", test.name, test.content, got, want)
+		}
+	}
+}
+
+// jsonEqual compares decoded documents, treating nil and empty arrays alike
+func jsonEqual(a, b interface{}) bool {
+	return len(diffValues(nil, a, b, diffOptions{arrayMatch: "index"})) == 0
+}
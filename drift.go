@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Longest value shown in a drift matrix cell before it is truncated
+const driftCellWidth = 40
+
+// driftRow is one leaf path and the value it has in each compared file
+type driftRow struct {
+	path      jsonPath
+	values    []interface{}
+	present   []bool
+	divergent bool
+}
+
+// buildDriftRows computes the union of leaf paths over documents and the
+// value of each path in every document, ordered by path.
+func buildDriftRows(documents []interface{}) []driftRow {
+	rows := map[string]*driftRow{}
+	for i, document := range documents {
+		walkLeaves(nil, document, func(path jsonPath, value interface{}) {
+			key := path.pointer()
+			row, ok := rows[key]
+			if !ok {
+				row = &driftRow{path: path, values: make([]interface{}, len(documents)), present: make([]bool, len(documents))}
+				rows[key] = row
+			}
+			row.values[i] = value
+			row.present[i] = true
+		})
+	}
+
+	result := make([]driftRow, 0, len(rows))
+	for _, row := range rows {
+		for i := range documents {
+			if !row.present[i] || !reflect.DeepEqual(row.values[i], row.values[0]) {
+				row.divergent = true
+				break
+			}
+		}
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool { return pathLess(result[i].path, result[j].path) })
+	return result
+}
+
+// majorityValue returns the index of a cell holding the most common value of a row
+func majorityValue(row driftRow) int {
+	best, bestCount := -1, 0
+	for i := range row.values {
+		if !row.present[i] {
+			continue
+		}
+		count := 0
+		for j := range row.values {
+			if row.present[j] && reflect.DeepEqual(row.values[i], row.values[j]) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = i, count
+		}
+	}
+	return best
+}
+
+// pathLess orders paths token by token, with array indices in numeric order
+func pathLess(a, b jsonPath) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ai, aIsIndex := a[i].(int)
+		bi, bIsIndex := b[i].(int)
+		switch {
+		case aIsIndex && bIsIndex:
+			if ai != bi {
+				return ai < bi
+			}
+		case aIsIndex != bIsIndex:
+			return aIsIndex
+		default:
+			if as, bs := a[i].(string), b[i].(string); as != bs {
+				return as < bs
+			}
+		}
+	}
+	return len(a) < len(b)
+}
+
+// walkLeaves calls fn for every scalar and every empty object or array in value
+func walkLeaves(path jsonPath, value interface{}, fn func(path jsonPath, value interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			fn(path, v)
+		}
+		for key, child := range v {
+			walkLeaves(path.child(key), child, fn)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			fn(path, v)
+		}
+		for i, child := range v {
+			walkLeaves(path.child(i), child, fn)
+		}
+	default:
+		fn(path, v)
+	}
+}
+
+// showDriftMatrix shows, for every leaf path of the marked files, which
+// value each file has. The files are read in the background. Cells that
+// differ from the most common value are highlighted.
+func (state *appState) showDriftMatrix() {
+	var files []string
+	for _, file := range state.allFiles {
+		if state.markedFiles[file] {
+			files = append(files, file)
+		}
+	}
+	if len(files) < 2 {
+		state.debugView.SetText("Mark at least two files with Space to build a drift matrix.")
+		return
+	}
+
+	state.runLoad(loadView, fmt.Sprintf("drift matrix of %d files", len(files)), func(ctx context.Context, job *loadJob) (func(), error) {
+		documents := make([]interface{}, len(files))
+		for i, file := range files {
+			job.phase.Store(loadReading)
+			job.read.Store(0)
+			value, err := job.readFile(ctx, file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			documents[i] = value
+		}
+		job.phase.Store(loadComparing)
+		rows := buildDriftRows(documents)
+		return func() {
+			state.showDriftRows(files, rows)
+		}, nil
+	})
+}
+
+// showDriftRows shows the drift matrix of files
+func (state *appState) showDriftRows(files []string, rows []driftRow) {
+	divergentCount := 0
+	for _, row := range rows {
+		if row.divergent {
+			divergentCount++
+		}
+	}
+
+	table := tview.NewTable().SetFixed(1, 1).SetSelectable(true, false)
+	table.SetBorder(true)
+
+	divergentOnly := false
+	render := func() {
+		table.Clear()
+		table.SetCell(0, 0, tview.NewTableCell("Path").SetTextColor(tcell.ColorGreen).SetSelectable(false))
+		for i, file := range files {
+			table.SetCell(0, i+1, tview.NewTableCell(tview.Escape(file)).SetTextColor(tcell.ColorGreen).SetSelectable(false))
+		}
+
+		r := 1
+		for _, row := range rows {
+			if divergentOnly && !row.divergent {
+				continue
+			}
+			pathCell := tview.NewTableCell(tview.Escape(row.path.String()))
+			if row.divergent {
+				pathCell.SetTextColor(tcell.ColorYellow)
+			}
+			table.SetCell(r, 0, pathCell)
+
+			majority := majorityValue(row)
+			for i := range files {
+				cell := tview.NewTableCell("—").SetTextColor(tcell.ColorGray)
+				if row.present[i] {
+					text := []rune(summarizeValue(row.values[i]))
+					if len(text) > driftCellWidth {
+						text = append(text[:driftCellWidth-1], '…')
+					}
+					cell = tview.NewTableCell(tview.Escape(string(text)))
+					if row.divergent && !reflect.DeepEqual(row.values[i], row.values[majority]) {
+						cell.SetTextColor(tcell.ColorRed)
+					}
+				} else if row.divergent {
+					cell.SetTextColor(tcell.ColorRed)
+				}
+				table.SetCell(r, i+1, cell)
+			}
+			r++
+		}
+
+		filter := "all paths"
+		if divergentOnly {
+			filter = "divergent paths only"
+		}
+		table.SetTitle(fmt.Sprintf("Drift matrix: %d paths, %d divergent, showing %s (d: toggle filter, Esc: close)", len(rows), divergentCount, filter))
+		table.ScrollToBeginning()
+	}
+	render()
+
+	table.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			state.returnToMain()
+		}
+	})
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune {
+			switch event.Rune() {
+			case 'd', 'D':
+				divergentOnly = !divergentOnly
+				render()
+				return nil
+			case 'q', 'Q':
+				state.returnToMain()
+				return nil
+			}
+		}
+		return event
+	})
+
+	state.app.SetRoot(table, true).SetFocus(table)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Longest snippet shown for a cross-file search match
+const snippetWidth = 80
+
+// fileMatch is one search result in one file of the file list
+type fileMatch struct {
+	file    string
+	result  searchResult
+	snippet string
+}
+
+// searchFile runs a query over one file and returns its matches with a
+// snippet of the matching line
+func searchFile(file string, query searchQuery, options searchOptions) ([]fileMatch, error) {
+	value, err := readJSONFile(file)
+	if err != nil {
+		return nil, err
+	}
+	text, lines := formatJSON(value)
+	results, err := searchDocument(text, lines, query, options)
+	if err != nil {
+		return nil, err
+	}
+
+	textLines := strings.Split(text, "\n")
+	matches := make([]fileMatch, 0, len(results))
+	for _, result := range results {
+		snippet := []rune(strings.TrimSpace(textLines[result.line]))
+		if len(snippet) > snippetWidth {
+			snippet = append(snippet[:snippetWidth-1], '…')
+		}
+		matches = append(matches, fileMatch{file: file, result: result, snippet: string(snippet)})
+	}
+	return matches, nil
+}
+
+// searchFiles searches files on a pool of goroutines and calls found with the
+// matches of each file that has any. Files that cannot be read or parsed are
+// logged and skipped. It returns early when ctx is cancelled.
+func searchFiles(ctx context.Context, files []string, query searchQuery, options searchOptions, found func([]fileMatch)) {
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				matches, err := searchFile(files[i], query, options)
+				if err != nil {
+					errorLogger.Printf("Skipping %s in cross-file search: %v", files[i], err)
+					continue
+				}
+				if len(matches) > 0 && ctx.Err() == nil {
+					found(matches)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range files {
+		select {
+		case indices <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indices)
+	wg.Wait()
+}
+
+// openFileMatch opens the file of a cross-file match and selects the match in it
+func (state *appState) openFileMatch(match fileMatch, search string) {
+	state.returnToMain()
+	state.searchString = search
+	state.searchPane = mainPane
+	state.searchBothPanes = false
+	state.openFile(match.file, func() {
+		for i, result := range state.searchResults {
+			if result.line == match.result.line && result.start == match.result.start {
+				state.currentSearchIndex = i
+				break
+			}
+		}
+		state.highlightCurrentResult()
+		if len(state.searchResults) > 0 {
+			state.showResultCounter()
+		}
+	})
+	state.focusContent()
+}
+
+// showFileSearch opens the cross-file search panel. Searches run in the
+// background and are cancelled when a new search starts or the panel closes.
+func (state *appState) showFileSearch() {
+	if len(state.allFiles) == 0 {
+		state.debugView.SetText("No files to search.")
+		return
+	}
+
+	input := tview.NewInputField().SetLabel("Search all files: ").SetText(state.fileSearchString)
+	status := tview.NewTextView().SetDynamicColors(true)
+	results := tview.NewList().ShowSecondaryText(false)
+	results.SetBorder(true).SetTitle("Matches (Enter: open, Tab: search field, Esc: close)")
+
+	var matches []fileMatch
+	var search string
+	addMatches := func(found []fileMatch) {
+		for _, match := range found {
+			match := match // capture range variable
+			matches = append(matches, match)
+			results.AddItem(fmt.Sprintf("[green]%s[-] [yellow]%s[-]  %s", tview.Escape(match.file),
+				tview.Escape(match.result.path.String()), tview.Escape(match.snippet)), "", 0, func() {
+				state.openFileMatch(match, search)
+			})
+		}
+	}
+	addMatches(state.fileMatches)
+	search = state.fileSearchString
+
+	cancel := func() {}
+	closePanel := func() {
+		cancel()
+		state.returnToMain()
+	}
+
+	start := func() {
+		cancel()
+		search = input.GetText()
+		query, err := parseSearchQuery(search)
+		if err != nil {
+			status.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+			return
+		}
+
+		results.Clear()
+		matches = nil
+		state.fileMatches = nil
+		state.fileSearchString = search
+		if search == "" {
+			status.SetText("")
+			return
+		}
+
+		var searchCtx context.Context
+		searchCtx, cancel = context.WithCancel(context.Background())
+		files, options := state.allFiles, state.searchOptions
+		status.SetText(fmt.Sprintf("Searching %d files…", len(files)))
+		go func() {
+			searchFiles(searchCtx, files, query, options, func(found []fileMatch) {
+				state.app.QueueUpdateDraw(func() {
+					if searchCtx.Err() != nil {
+						return // A newer search has started
+					}
+					addMatches(found)
+					state.fileMatches = matches
+					status.SetText(fmt.Sprintf("Searching %d files… %d matches", len(files), len(matches)))
+				})
+			})
+			state.app.QueueUpdateDraw(func() {
+				if searchCtx.Err() != nil {
+					return
+				}
+				status.SetText(fmt.Sprintf("%d matches in %d files (%s)", len(matches), len(files), options))
+			})
+		}()
+	}
+
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			start()
+		case tcell.KeyEscape:
+			closePanel()
+		case tcell.KeyTab:
+			if results.GetItemCount() > 0 {
+				state.app.SetFocus(results)
+			}
+		}
+	})
+	results.SetDoneFunc(closePanel)
+	results.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			state.app.SetFocus(input)
+			return nil
+		}
+		return event
+	})
+
+	panel := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(input, 1, 0, true).
+		AddItem(results, 0, 1, false).
+		AddItem(status, 1, 0, false)
+	panel.SetBorder(true).SetTitle("Search in files")
+
+	state.app.SetRoot(panel, true).SetFocus(input)
+	if len(matches) > 0 {
+		status.SetText(fmt.Sprintf("%d matches from the last search", len(matches)))
+		state.app.SetFocus(results)
+	}
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// fileRow is one entry of the file list: a file, or a directory of the tree
+type fileRow struct {
+	path      string // file path, or directory path for directory rows
+	dir       bool
+	depth     int
+	count     int   // JSON files below a directory
+	positions []int // runes of the relative path matched by the fuzzy filter
+}
+
+// dirNode is a directory of the file tree
+type dirNode struct {
+	path  string
+	dirs  map[string]*dirNode
+	files []string
+	count int
+}
+
+// buildFileTree arranges files found under root into a directory tree
+func buildFileTree(root string, files []string) *dirNode {
+	tree := &dirNode{path: root, dirs: map[string]*dirNode{}}
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			rel = file
+		}
+		node := tree
+		node.count++
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node.dirs[part]
+			if !ok {
+				child = &dirNode{path: filepath.Join(node.path, part), dirs: map[string]*dirNode{}}
+				node.dirs[part] = child
+			}
+			node = child
+			node.count++
+		}
+		node.files = append(node.files, file)
+	}
+	return tree
+}
+
+// relativePath shows a file relative to the root directory of the file list
+func relativePath(root, file string) string {
+	if rel, err := filepath.Rel(root, file); err == nil {
+		return rel
+	}
+	return file
+}
+
+// rows flattens the visible part of the tree, directories first and files in
+// the order of less. Only the children of expanded directories are listed.
+func (node *dirNode) rows(expanded map[string]bool, depth int, less func(a, b string) bool) []fileRow {
+	names := make([]string, 0, len(node.dirs))
+	for name := range node.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rows []fileRow
+	for _, name := range names {
+		child := node.dirs[name]
+		rows = append(rows, fileRow{path: child.path, dir: true, depth: depth, count: child.count})
+		if expanded[child.path] {
+			rows = append(rows, child.rows(expanded, depth+1, less)...)
+		}
+	}
+	files := append([]string(nil), node.files...)
+	sort.Slice(files, func(i, j int) bool { return less(files[i], files[j]) })
+	for _, file := range files {
+		rows = append(rows, fileRow{path: file, depth: depth})
+	}
+	return rows
+}
+
+// addFileRow appends a row to the file list, keeping the files, action funcs
+// and active file index in step with it
+func (state *appState) addFileRow(row fileRow) {
+	action := func() {
+		state.openFile(row.path, nil)
+	}
+	file := row.path
+	if row.dir {
+		action = func() {
+			state.toggleDirectory(row.path)
+		}
+		file = ""
+	}
+	if file != "" && file == state.activeFile {
+		state.activeFileIndex = len(state.fileRows)
+	}
+	state.fileRowIndex[row.path] = len(state.fileRows)
+	state.fileRows = append(state.fileRows, row)
+	state.files = append(state.files, file)
+	state.actionFuncs = append(state.actionFuncs, action)
+	state.fileList.AddItem(state.fileItemText(row), "", 0, action)
+}
+
+// appendFileRows adds the rows that files found by a running walk make
+// visible to the end of the file list, so that a batch costs only its own
+// rows. Directories already listed get their counts updated in place. The
+// rows are sorted, and their columns lined up, by refreshFileList once the
+// walk finishes.
+func (state *appState) appendFileRows(found []string) {
+	active := state.activeFileIndex
+	if state.fileFilter != "" {
+		relative := make([]string, len(found))
+		for i, file := range found {
+			relative[i] = relativePath(state.rootDir, file)
+		}
+		for _, match := range fuzzyFilter(state.fileFilter, relative) {
+			state.addFileRow(fileRow{path: found[match.index], positions: match.positions})
+		}
+	} else {
+		for _, file := range found {
+			var dirs []string // The directories leading to file, innermost first
+			for dir := filepath.Dir(file); relativePath(state.rootDir, dir) != "." && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+				dirs = append(dirs, dir)
+			}
+			visible := true
+			for depth := 0; depth < len(dirs) && visible; depth++ {
+				dir := dirs[len(dirs)-1-depth]
+				if index, ok := state.fileRowIndex[dir]; ok {
+					state.fileRows[index].count++
+					state.fileList.SetItemText(index, state.fileItemText(state.fileRows[index]), "")
+				} else {
+					state.addFileRow(fileRow{path: dir, dir: true, depth: depth, count: 1})
+				}
+				visible = state.expandedDirs[dir]
+			}
+			if visible {
+				state.addFileRow(fileRow{path: file, depth: len(dirs)})
+			}
+		}
+	}
+	if state.activeFileIndex != active {
+		updateActiveFileHighlight(state.fileList, state.activeFileIndex)
+	}
+	state.setFileListTitle()
+}
+
+// expandToFile expands the directories leading to file and reports whether any was collapsed
+func (state *appState) expandToFile(file string) bool {
+	changed := false
+	for dir := filepath.Dir(file); dir != "." && dir != string(filepath.Separator) && relativePath(state.rootDir, dir) != "."; dir = filepath.Dir(dir) {
+		if !state.expandedDirs[dir] {
+			state.expandedDirs[dir] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// fileItemText is the file list entry of a row: a directory with its count,
+// or a file with its mark, fuzzy matches, columns and validation badge
+func (state *appState) fileItemText(row fileRow) string {
+	indent := strings.Repeat("  ", row.depth)
+	if row.dir {
+		arrow := "▸ "
+		if state.expandedDirs[row.path] {
+			arrow = "▾ "
+		}
+		return fmt.Sprintf("%s%s%s/ [gray](%d)[-]", indent, arrow, tview.Escape(filepath.Base(row.path)), row.count)
+	}
+
+	var text string
+	if state.fileFilter != "" {
+		text = highlightPositions(relativePath(state.rootDir, row.path), row.positions)
+	} else {
+		text = indent + "  " + tview.Escape(filepath.Base(row.path))
+	}
+	if state.markedFiles[row.path] {
+		text = fileMarker + text
+	}
+	if columns := state.columnsText(row.path); columns != "" {
+		if pad := state.fileNameWidth - tview.TaggedStringWidth(text); pad > 0 {
+			text += strings.Repeat(" ", pad)
+		}
+		text += columns
+	}
+	return text + " " + statusBadges[state.fileStatus(row.path)]
+}
+
+// refreshFileList rebuilds the file list: the directory tree, or the files
+// matching the fuzzy filter, best first. The files, action funcs and active
+// file index are kept in step with the visible rows.
+func (state *appState) refreshFileList() {
+	var rows []fileRow
+	if state.fileFilter == "" {
+		rows = buildFileTree(state.rootDir, state.allFiles).rows(state.expandedDirs, 0, state.lessFiles)
+	} else {
+		relative := make([]string, len(state.allFiles))
+		for i, file := range state.allFiles {
+			relative[i] = relativePath(state.rootDir, file)
+		}
+		for _, match := range fuzzyFilter(state.fileFilter, relative) {
+			rows = append(rows, fileRow{path: state.allFiles[match.index], positions: match.positions})
+		}
+	}
+
+	// Line the columns up after the longest name, leaving room for a mark
+	state.fileNameWidth = 0
+	for _, row := range rows {
+		width := tview.TaggedStringWidth(fileMarker) + len([]rune(relativePath(state.rootDir, row.path)))
+		if state.fileFilter == "" {
+			width = tview.TaggedStringWidth(fileMarker) + 2*row.depth + 2 + len([]rune(filepath.Base(row.path)))
+		}
+		if !row.dir && width > state.fileNameWidth {
+			state.fileNameWidth = width
+		}
+	}
+	if state.fileNameWidth > maxFileNameWidth {
+		state.fileNameWidth = maxFileNameWidth
+	}
+
+	current := state.fileList.GetCurrentItem()
+	state.fileList.Clear()
+	state.actionFuncs = nil
+	state.files = nil
+	state.fileRows = nil
+	state.fileRowIndex = map[string]int{}
+	state.activeFileIndex = -1
+	for _, row := range rows {
+		state.addFileRow(row)
+	}
+	updateActiveFileHighlight(state.fileList, state.activeFileIndex)
+	if current < len(rows) {
+		state.fileList.SetCurrentItem(current)
+	}
+	state.setFileListTitle()
+}
+
+// setFileListTitle shows the number of files listed, and of those matching
+// the fuzzy filter, in the title of the file list
+func (state *appState) setFileListTitle() {
+	details := ""
+	if invalid := state.countInvalid(); invalid > 0 {
+		details = fmt.Sprintf(", %d invalid", invalid)
+	}
+	if state.sortOrder != sortByName && state.fileFilter == "" {
+		details += ", by " + sortOrderNames[state.sortOrder]
+	}
+	if state.walking {
+		details += ", scanning…"
+	}
+	if state.fileFilter == "" {
+		state.fileList.SetTitle(fmt.Sprintf("Files in %s (%d%s)", tview.Escape(state.rootDir), len(state.allFiles), details))
+		return
+	}
+	state.fileList.SetTitle(fmt.Sprintf("Files ~ %s (%d of %d%s)", tview.Escape(state.fileFilter), len(state.fileRows), len(state.allFiles), details))
+}
+
+// showRootForm asks for a new root directory for the file list
+func (state *appState) showRootForm() {
+	if state.leftRoot != "" {
+		state.debugView.SetText("The root cannot be changed when comparing directories.")
+		return
+	}
+
+	input := tview.NewInputField().SetLabel("Root directory: ").SetText(state.rootDir).SetFieldWidth(60)
+	form := tview.NewForm().AddFormItem(input)
+	open := func() {
+		dir := filepath.Clean(input.GetText())
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			form.SetTitle("Change root: [red]" + tview.Escape(dir) + " is not a directory[-]")
+			return
+		}
+		state.returnToMain()
+		state.rootDir = dir
+		state.fileFilter = ""
+		state.fileFilterInput.SetText("")
+
+		state.reloadFiles()
+	}
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			open()
+		}
+	})
+	form.AddButton("Open", open).
+		AddButton("Cancel", state.returnToMain).
+		SetCancelFunc(state.returnToMain)
+	form.SetBorder(true).SetTitle("Change root")
+
+	state.app.SetRoot(form, true).SetFocus(form)
+}
+
+// toggleDirectory expands or collapses a directory of the file tree
+func (state *appState) toggleDirectory(dir string) {
+	if state.expandedDirs[dir] {
+		delete(state.expandedDirs, dir)
+	} else {
+		state.expandedDirs[dir] = true
+	}
+	state.refreshFileList()
+}
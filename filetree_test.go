@@ -0,0 +1,44 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAppendFileRows(t *testing.T) {
+	root := filepath.Join("data", "root")
+	files := []string{
+		filepath.Join(root, "b", "x.json"),
+		filepath.Join(root, "a.json"),
+		filepath.Join(root, "b", "c", "y.json"),
+		filepath.Join(root, "d", "z.json"),
+		filepath.Join(root, "b", "w.json"),
+	}
+	state, ui := startTestApp(t)
+
+	sorted := func(rows []fileRow) []fileRow {
+		rows = append([]fileRow(nil), rows...)
+		sort.Slice(rows, func(i, j int) bool { return rows[i].path < rows[j].path })
+		return rows
+	}
+	// Rows streamed in batches are the rows a rebuild lists, in another order
+	ui(func() {
+		state.rootDir = root
+		state.expandedDirs = map[string]bool{filepath.Join(root, "b"): true}
+		state.refreshFileList()
+		for _, batch := range [][]string{files[:2], files[2:]} {
+			state.allFiles = append(state.allFiles, batch...)
+			state.appendFileRows(batch)
+		}
+		streamed := sorted(state.fileRows)
+		state.refreshFileList()
+		if rebuilt := sorted(state.fileRows); !reflect.DeepEqual(streamed, rebuilt) {
+			t.Errorf("streamed rows %+v, want %+v", streamed, rebuilt)
+		}
+		if got, want := state.fileList.GetItemCount(), len(state.fileRows); got != want {
+			t.Errorf("list has %d items for %d rows", got, want)
+		}
+	})
+}
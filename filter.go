@@ -0,0 +1,101 @@
+package main
+
+import "github.com/rivo/tview"
+
+// formattedDocument is a document as shown in the content pane
+type formattedDocument struct {
+	value   interface{}
+	text    string // plain pretty-printed text
+	colored string // text with colour tags
+	lines   []contentLine
+}
+
+// nodeFilter selects the nodes of a filter view: matched nodes are shown
+// with their whole subtree, their ancestors only with the children that
+// lead to a match. Both sets are keyed by JSON Pointer.
+type nodeFilter struct {
+	matched   map[string]bool
+	ancestors map[string]bool
+}
+
+// anchorLine returns the first line showing anchor, or the node closest to
+// it that is still visible
+func anchorLine(lines []contentLine, anchor jsonPath) int {
+	best, bestLength := 0, -1
+	for i, line := range lines {
+		if line.closing || line.elided || len(line.path) > len(anchor) || len(line.path) <= bestLength {
+			continue
+		}
+		prefix := true
+		for j := range line.path {
+			if line.path[j] != anchor[j] {
+				prefix = false
+				break
+			}
+		}
+		if prefix {
+			best, bestLength = i, len(line.path)
+		}
+	}
+	return best
+}
+
+// newFormattedDocument formats value, narrowed to the nodes of filter when it is not nil
+func newFormattedDocument(value interface{}, filter *nodeFilter) formattedDocument {
+	text, lines := formatNodes(value, filter)
+	return formattedDocument{value: value, text: text, colored: colorizeJSON(text), lines: lines}
+}
+
+// newNodeFilter selects the nodes holding search results
+func newNodeFilter(results []searchResult) *nodeFilter {
+	filter := &nodeFilter{matched: map[string]bool{}, ancestors: map[string]bool{}}
+	for _, result := range results {
+		filter.matched[result.path.pointer()] = true
+		for i := 0; i < len(result.path); i++ {
+			filter.ancestors[result.path[:i].pointer()] = true
+		}
+	}
+	return filter
+}
+
+// toggleFilterView switches between the whole documents and only the nodes
+// matching the current search, keeping the node at the top of each pane in view
+func (state *appState) toggleFilterView() {
+	var anchors [len(state.panes)]jsonPath
+	for i, pane := range state.panes {
+		if pane.view == nil {
+			continue
+		}
+		if row, _ := pane.view.GetScrollOffset(); row < len(pane.lines) {
+			anchors[i] = pane.lines[row].path
+		}
+	}
+
+	state.filterView = !state.filterView
+	if err := state.updateSearchResults(); err != nil {
+		errorLogger.Printf("Search for %q failed: %v", state.searchString, err)
+	}
+	for i, pane := range state.panes {
+		if pane.view == nil || (i != mainPane && pane.lines == nil) {
+			continue
+		}
+		results, ids := state.paneResults(i)
+		pane.view.SetText(highlightSearchResult(pane.text, results, ids))
+		pane.view.Highlight()
+		pane.view.ScrollTo(anchorLine(pane.lines, anchors[i]), 0)
+	}
+	if len(state.searchResults) > 0 {
+		result := state.searchResults[state.currentSearchIndex]
+		state.panes[result.pane].view.Highlight(searchRegion(state.currentSearchIndex))
+	}
+	state.refreshMatchList()
+
+	switch {
+	case !state.filterView:
+		state.debugView.SetText("Filter view off.")
+	case state.searchString == "":
+		state.debugView.SetText("Filter view on. Search with / to show only the matching nodes.")
+	default:
+		state.debugView.SetText("Filter view on: showing the nodes matching " + tview.Escape(state.searchString) + ". Press v to show everything.")
+	}
+}
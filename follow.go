@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rivo/tview"
+)
+
+// How often a followed file is checked for appended lines
+const followInterval = 250 * time.Millisecond
+
+// followState is the JSON Lines file shown in follow mode and the records
+// read from it so far
+type followState struct {
+	file    string
+	records []interface{}
+	skipped int // lines that were not valid JSON
+	cancel  context.CancelFunc
+	rows    int // rendered rows of the content pane at width
+	width   int
+}
+
+// logTail reads the lines appended to a file since the last read, starting
+// over when the file is truncated or replaced by rotation
+type logTail struct {
+	file    string
+	info    os.FileInfo // of the file last read, to notice rotation
+	offset  int64
+	partial []byte // an incomplete last line, kept until its newline arrives
+}
+
+// formatRecords formats records numbered from first one after another, as
+// they are in the file, so that new records can be appended to the text
+// without touching what is already shown. Paths point into the array of all
+// records.
+func formatRecords(records []interface{}, first int) formattedDocument {
+	texts := make([]string, len(records))
+	var lines []contentLine
+	for i, record := range records {
+		var recordLines []contentLine
+		texts[i], recordLines = formatNodes(record, nil)
+		for _, line := range recordLines {
+			line.path = append(jsonPath{first + i}, line.path...)
+			lines = append(lines, line)
+		}
+	}
+	text := strings.Join(texts, "\n")
+	return formattedDocument{value: records, text: text, colored: colorizeJSON(text), lines: lines}
+}
+
+// isJSONLines reports whether file holds one JSON document per line
+func isJSONLines(file string) bool {
+	name := strings.ToLower(file)
+	return strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".ndjson")
+}
+
+// renderedRows counts the rows plain text takes up in a wrapping pane of
+// width columns
+func renderedRows(text string, width int) int {
+	if text == "" || width <= 0 {
+		return 0
+	}
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		lineWidth := len(line)
+		for i := 0; i < len(line); i++ {
+			if line[i] >= utf8.RuneSelf {
+				lineWidth = tview.TaggedStringWidth(tview.Escape(line))
+				break
+			}
+		}
+		rows += max(1, (lineWidth+width-1)/width)
+	}
+	return rows
+}
+
+// read returns the complete lines appended since the last read. The event
+// is "truncated" or "rotated" when reading started over from the beginning.
+func (t *logTail) read() ([][]byte, string, error) {
+	info, err := os.Stat(t.file)
+	if err != nil {
+		return nil, "", err // Likely mid-rotation; the next read finds the new file
+	}
+	event := ""
+	switch {
+	case t.info != nil && !os.SameFile(t.info, info):
+		event = "rotated"
+	case info.Size() < t.offset:
+		event = "truncated"
+	}
+	if event != "" {
+		t.offset, t.partial = 0, nil
+	}
+	t.info = info
+	if info.Size() == t.offset {
+		return nil, event, nil
+	}
+
+	f, err := os.Open(t.file)
+	if err != nil {
+		return nil, event, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, event, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, event, err
+	}
+	t.offset += int64(len(data))
+
+	data = append(t.partial, data...)
+	end := bytes.LastIndexByte(data, '\n')
+	t.partial = append([]byte(nil), data[end+1:]...)
+	var lines [][]byte
+	for _, line := range bytes.Split(data[:end+1], []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines, event, nil
+}
+
+// appendFollowedRecords adds records read from the followed file, formatted
+// as chunk, to the content pane. Only the new records are searched and
+// written to the pane, unless the filter view or a search of both panes needs
+// the whole document laid out again. The pane keeps scrolling with new records
+// while it is at the end, and stays put once scrolled up.
+func (state *appState) appendFollowedRecords(chunk formattedDocument, skipped int, event string) {
+	follow := state.follow
+	pane := &state.panes[mainPane]
+	incremental := !state.filterView && len(state.searchedPanes()) == 1
+	row, column := state.fileContent.GetScrollOffset()
+	_, _, width, height := state.fileContent.GetInnerRect()
+	if !incremental || width != follow.width {
+		text := pane.document.text
+		if state.filterView {
+			text = contentTagRegex.ReplaceAllString(pane.text, "")
+		}
+		follow.rows, follow.width = renderedRows(text, width), width
+	}
+	atEnd := row+height >= follow.rows
+
+	follow.records = append(follow.records, chunk.value.([]interface{})...)
+	follow.skipped += skipped
+	separator := ""
+	if pane.document.text != "" && chunk.text != "" {
+		separator = "\n"
+	}
+	pane.document.value = follow.records
+	pane.document.text += separator + chunk.text
+	pane.document.colored += separator + chunk.colored
+	pane.document.lines = append(pane.document.lines, chunk.lines...)
+
+	if incremental {
+		if err := state.appendFollowedText(separator, chunk); err != nil {
+			errorLogger.Printf("Search for %q failed: %v", state.searchString, err)
+		}
+		follow.rows += renderedRows(chunk.text, width)
+	} else {
+		state.setContentDocument(follow.file, pane.document)
+		follow.width = 0 // Count the rows again once appending is incremental
+	}
+	state.fileContent.SetTitle(filepath.Base(follow.file) + " (following)")
+	if atEnd {
+		state.fileContent.ScrollToEnd()
+	} else if !incremental {
+		state.fileContent.ScrollTo(row, column)
+	}
+
+	status := fmt.Sprintf("Following %s: %d records", filepath.Base(follow.file), len(follow.records))
+	if follow.skipped > 0 {
+		status += fmt.Sprintf(", %d invalid lines skipped", follow.skipped)
+	}
+	if event != "" {
+		status += ", file " + event
+	}
+	if !atEnd {
+		status += " [yellow](scrolled up: auto-scroll paused, scroll to the end to resume)[-]"
+	}
+	state.debugView.SetText(status + ". Press t to stop.")
+}
+
+// appendFollowedText writes newly formatted records to the end of the content
+// pane, marking the matches of the current search in them
+func (state *appState) appendFollowedText(separator string, chunk formattedDocument) error {
+	pane := &state.panes[mainPane]
+	offset := len(pane.document.lines) - len(chunk.lines)
+	pane.text, pane.lines = pane.document.colored, pane.document.lines
+	if chunk.text == "" {
+		return nil
+	}
+	if state.searchString == "" {
+		_, err := state.fileContent.Write([]byte(separator + chunk.colored))
+		return err
+	}
+
+	query, err := parseSearchQuery(state.searchString)
+	if err != nil {
+		return err
+	}
+	results, err := searchDocument(chunk.text, chunk.lines, query, state.searchOptions)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, len(results))
+	for i := range results {
+		ids[i] = len(state.searchResults) + i
+	}
+	highlighted := highlightSearchResult(chunk.colored, results, ids)
+	for _, result := range results {
+		result.pane, result.line = mainPane, result.line+offset
+		state.searchResults = append(state.searchResults, result)
+	}
+	if len(results) > 0 {
+		state.refreshMatchList()
+	}
+	_, err = state.fileContent.Write([]byte(separator + highlighted))
+	return err
+}
+
+// startFollow shows a JSON Lines file as it grows, like tail -f: the file
+// selected in the file list while it has the focus, otherwise the open file.
+// Lines that are not valid JSON are skipped rather than failing the file.
+func (state *appState) startFollow() {
+	file := state.activeFile
+	if index := state.fileList.GetCurrentItem(); state.isFileListFocused && index < len(state.files) && state.files[index] != "" {
+		file = state.files[index]
+	}
+	if file == "" || !isJSONLines(file) {
+		state.debugView.SetText("Select or open a .jsonl or .ndjson file to follow it.")
+		return
+	}
+	if state.blockedByEdits() {
+		return
+	}
+	state.activeFileIndex = -1
+	for i, listed := range state.files {
+		if listed == file {
+			state.activeFileIndex = i
+		}
+	}
+	updateActiveFileHighlight(state.fileList, state.activeFileIndex)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	state.follow = &followState{file: file, cancel: cancel}
+	follow := state.follow
+	state.setContentDocument(file, formattedDocument{value: []interface{}{}})
+	state.fileContent.SetTitle(filepath.Base(file) + " (following)")
+	state.debugView.SetText("Reading " + tview.Escape(filepath.Base(file)) + "…")
+
+	go func() {
+		tail := &logTail{file: file}
+		count := 0
+		ticker := time.NewTicker(followInterval)
+		defer ticker.Stop()
+		for first := true; ; first = false {
+			lines, event, err := tail.read()
+			if err != nil && !os.IsNotExist(err) {
+				errorLogger.Printf("Failed to follow %s: %v", file, err)
+			}
+			var records []interface{}
+			skipped := 0
+			for _, line := range lines {
+				var record interface{}
+				if err := json.Unmarshal(line, &record); err != nil {
+					errorLogger.Printf("Skipping invalid line in %s: %v", file, err)
+					skipped++
+					continue
+				}
+				records = append(records, record)
+			}
+			if first || len(records) > 0 || skipped > 0 || event != "" {
+				chunk := formatRecords(records, count)
+				count += len(records)
+				state.app.QueueUpdateDraw(func() {
+					if state.follow == follow {
+						state.appendFollowedRecords(chunk, skipped, event)
+					}
+				})
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopFollow leaves follow mode, keeping the records read so far on screen
+func (state *appState) stopFollow() {
+	if state.follow == nil {
+		return
+	}
+	state.follow.cancel()
+	state.fileContent.SetTitle(filepath.Base(state.follow.file))
+	state.debugView.SetText(fmt.Sprintf("Stopped following %s.", filepath.Base(state.follow.file)))
+	state.follow = nil
+}
+
+// toggleFollow starts or stops following the open file
+func (state *appState) toggleFollow() {
+	if state.follow != nil {
+		state.stopFollow()
+		return
+	}
+	state.startFollow()
+}
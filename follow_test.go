@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatRecords(t *testing.T) {
+	document := formatRecords([]interface{}{map[string]interface{}{"a": 1.0}, "x"}, 5)
+	if want := "{\n  \"a\": 1\n}\n\"x\""; document.text != want {
+		t.Errorf("text = %q, want %q", document.text, want)
+	}
+	var paths []string
+	for _, line := range document.lines {
+		paths = append(paths, line.path.String())
+	}
+	want := []string{jsonPath{5}.String(), jsonPath{5, "a"}.String(), jsonPath{5}.String(), jsonPath{6}.String()}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestRenderedRows(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  int
+	}{
+		{"", 10, 0},
+		{"abc", 10, 1},
+		{"abc\n\ndef", 10, 3},
+		{"0123456789", 10, 1},
+		{"0123456789a", 10, 2},
+		{"日本語日本語", 4, 3}, // Wide characters take two columns
+		{"[red]", 3, 2},  // Plain text, so brackets are not tags
+	}
+	for _, test := range tests {
+		if got := renderedRows(test.text, test.width); got != test.want {
+			t.Errorf("renderedRows(%q, %d) = %d, want %d", test.text, test.width, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Scores of the fuzzy matcher, modelled on fzf
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusConsecutive = 8
+	fuzzyBonusBoundary    = 10
+	fuzzyBonusCamel       = 7
+	fuzzyPenaltyGapStart  = 3
+	fuzzyPenaltyGapExtend = 1
+)
+
+// fuzzyMatch is an item that matched the fuzzy filter and the runes it matched at
+type fuzzyMatch struct {
+	index     int
+	score     int
+	positions []int
+}
+
+// fuzzyFilter returns the items matching pattern, best first. An empty
+// pattern matches every item in its original order.
+func fuzzyFilter(pattern string, items []string) []fuzzyMatch {
+	matches := make([]fuzzyMatch, 0, len(items))
+	for i, item := range items {
+		if score, positions, ok := fuzzyScore(pattern, item); ok {
+			matches = append(matches, fuzzyMatch{index: i, score: score, positions: positions})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(items[matches[i].index]) < len(items[matches[j].index])
+	})
+	return matches
+}
+
+// fuzzyScore matches the runes of pattern in order within text and scores
+// the best placement: consecutive runes and runes at the start of a path
+// segment or word score higher, gaps lower. Matching ignores case unless the
+// pattern contains an upper case letter.
+func fuzzyScore(pattern, text string) (int, []int, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	caseSensitive := strings.ToLower(pattern) != pattern
+	equal := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+	bonus := func(i int) int {
+		if i == 0 {
+			return fuzzyBonusBoundary
+		}
+		switch prev := t[i-1]; {
+		case strings.ContainsRune("/\\-_. ", prev):
+			return fuzzyBonusBoundary
+		case unicode.IsLower(prev) && unicode.IsUpper(t[i]):
+			return fuzzyBonusCamel
+		}
+		return 0
+	}
+
+	bestScore, found := 0, false
+	var bestPositions []int
+	for start := range t {
+		if !equal(t[start], p[0]) {
+			continue
+		}
+		// Match the rest greedily, then tighten the window from its end
+		positions := []int{start}
+		for i, j := start+1, 1; i < len(t) && j < len(p); i++ {
+			if equal(t[i], p[j]) {
+				positions = append(positions, i)
+				j++
+			}
+		}
+		if len(positions) < len(p) {
+			break // No later start can match either
+		}
+		for i, j := positions[len(p)-1], len(p)-1; j >= 0; i-- {
+			if equal(t[i], p[j]) {
+				positions[j] = i
+				j--
+			}
+		}
+
+		score := 0
+		for k, pos := range positions {
+			score += fuzzyScoreMatch + bonus(pos)
+			if k == 0 {
+				continue
+			}
+			if gap := pos - positions[k-1] - 1; gap == 0 {
+				score += fuzzyBonusConsecutive
+			} else {
+				score -= fuzzyPenaltyGapStart + (gap-1)*fuzzyPenaltyGapExtend
+			}
+		}
+		if !found || score > bestScore {
+			bestScore, bestPositions, found = score, positions, true
+		}
+	}
+	return bestScore, bestPositions, found
+}
+
+// highlightPositions escapes text for a tview list and emphasises the runes at positions
+func highlightPositions(text string, positions []int) string {
+	if len(positions) == 0 {
+		return tview.Escape(text)
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+
+	var out strings.Builder
+	for i, r := range []rune(text) {
+		if matched[i] {
+			out.WriteString("[::bu]" + tview.Escape(string(r)) + "[::-]")
+		} else {
+			out.WriteString(tview.Escape(string(r)))
+		}
+	}
+	return out.String()
+}
+
+// hideFileFilter removes the filter field from above the file list
+func (state *appState) hideFileFilter() {
+	state.filePanel.Clear().AddItem(state.fileList, 0, 1, true)
+	state.isFileListFocused = true
+	updatePaneFocus(state.fileList, state.fileContent, state.isFileListFocused)
+	state.app.SetFocus(state.fileList)
+}
+
+// setupFileFilter prepares the fuzzy filter field shown above the file list by 'p'
+func (state *appState) setupFileFilter() {
+	state.fileFilterInput = tview.NewInputField().SetLabel("Find file: ").SetFieldBackgroundColor(tcell.ColorDefault)
+	state.fileFilterInput.SetChangedFunc(func(text string) {
+		state.fileFilter = text
+		state.refreshFileList()
+		state.fileList.SetCurrentItem(0)
+	})
+	state.fileFilterInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp, tcell.KeyCtrlP:
+			if current := state.fileList.GetCurrentItem(); current > 0 {
+				state.fileList.SetCurrentItem(current - 1)
+			}
+			return nil
+		case tcell.KeyDown, tcell.KeyCtrlN:
+			if current := state.fileList.GetCurrentItem(); current < state.fileList.GetItemCount()-1 {
+				state.fileList.SetCurrentItem(current + 1)
+			}
+			return nil
+		}
+		return event
+	})
+	state.fileFilterInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			state.hideFileFilter()
+			if index := state.fileList.GetCurrentItem(); index < len(state.actionFuncs) {
+				state.actionFuncs[index]()
+				state.focusContent()
+			}
+		case tcell.KeyEscape:
+			state.fileFilterInput.SetText("") // Runs the changed func, which clears the filter
+			state.hideFileFilter()
+		case tcell.KeyTab:
+			state.hideFileFilter()
+		}
+	})
+}
+
+// showFileFilter shows the fuzzy filter field above the file list
+func (state *appState) showFileFilter() {
+	if state.leftRoot != "" {
+		state.debugView.SetText("The file finder is not available when comparing directories.")
+		return
+	}
+	state.filePanel.Clear().
+		AddItem(state.fileFilterInput, 1, 0, true).
+		AddItem(state.fileList, 0, 1, false)
+	state.isFileListFocused = true
+	updatePaneFocus(state.fileList, state.fileContent, state.isFileListFocused)
+	state.blurComparePane()
+	state.app.SetFocus(state.fileFilterInput)
+	state.debugView.SetText("Type to narrow the file list. Enter: open, ↑/↓: select, Tab: keep filter, Esc: clear filter")
+}
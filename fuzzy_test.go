@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		match         bool
+		positions     []int
+	}{
+		{"", "anything", true, nil},
+		{"abc", "a-b-c", true, []int{0, 2, 4}},
+		{"abc", "acb", false, nil},
+		{"ABC", "abc", false, nil}, // An upper case letter makes the match case-sensitive
+		{"abc", "ABC", true, []int{0, 1, 2}},
+		{"cfg", "src/config.json", true, []int{4, 7, 9}},
+		{"main", "domain/main.json", true, []int{7, 8, 9, 10}}, // The segment start beats the earlier match
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+" in "+test.text, func(t *testing.T) {
+			_, positions, ok := fuzzyScore(test.pattern, test.text)
+			if ok != test.match {
+				t.Fatalf("matched = %v, want %v", ok, test.match)
+			}
+			if ok && !reflect.DeepEqual(positions, test.positions) {
+				t.Errorf("positions = %v, want %v", positions, test.positions)
+			}
+		})
+	}
+}
+
+func TestFuzzyFilterOrder(t *testing.T) {
+	tests := []struct {
+		pattern string
+		items   []string
+		want    []string
+	}{
+		{"", []string{"b", "a"}, []string{"b", "a"}},
+		{"conf", []string{"a/deconflict.json", "config.json", "xcxoxnxf.json"}, []string{"config.json", "a/deconflict.json", "xcxoxnxf.json"}},
+		{"ab", []string{"xxab", "ab"}, []string{"ab", "xxab"}},
+		{"zz", []string{"a", "b"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			var got []string
+			for _, match := range fuzzyFilter(test.pattern, test.items) {
+				got = append(got, test.items[match.index])
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Number of commits offered by the revision picker
+const gitLogLimit = 200
+
+// gitRevision is one commit that touched a file
+type gitRevision struct {
+	hash, date, author, subject string
+}
+
+// gitCommand runs git in the directory holding file and returns its output.
+// git is killed once ctx is cancelled.
+func gitCommand(ctx context.Context, file string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = filepath.Dir(file)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// gitLog lists the most recent commits that changed file
+func gitLog(ctx context.Context, file string) ([]gitRevision, error) {
+	output, err := gitCommand(ctx, file, "log", fmt.Sprintf("-n%d", gitLogLimit), "--date=short", "--format=%h%x09%ad%x09%an%x09%s", "--", filepath.Base(file))
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []gitRevision
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		revisions = append(revisions, gitRevision{hash: fields[0], date: fields[1], author: fields[2], subject: fields[3]})
+	}
+	return revisions, nil
+}
+
+// gitShow returns the content of file as it was committed at revision
+func gitShow(ctx context.Context, file, revision string) ([]byte, error) {
+	// "./name" makes git resolve the path relative to cmd.Dir rather than the repository root
+	return gitCommand(ctx, file, "show", revision+":./"+filepath.Base(file))
+}
+
+// compareWithRevision shows the committed version of the active file next to
+// the working copy and diffs revision → working copy.
+func (state *appState) compareWithRevision(revision string) {
+	file := state.activeFile
+	if file == "" {
+		state.debugView.SetText("Open a file with Enter to compare it against git.")
+		return
+	}
+
+	options := state.diffOptions
+	label := file + "@" + revision
+	state.runLoad(loadCompare, filepath.Base(label), func(ctx context.Context, job *loadJob) (func(), error) {
+		content, err := gitShow(ctx, file, revision)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadParsing)
+		committed, err := parseDocument(file, content)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s at %s: %w", file, revision, err)
+		}
+		job.phase.Store(loadReading)
+		working, err := job.readFile(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadFormatting)
+		document := newFormattedDocument(committed, nil)
+		job.phase.Store(loadComparing)
+		cmp := &comparison{
+			leftPath:  label,
+			rightPath: file,
+			left:      committed,
+			right:     working,
+			options:   options,
+			ops:       diffValues(nil, committed, working, options),
+		}
+
+		return func() {
+			state.showComparePane(label, document)
+			state.comparison = cmp
+			if len(cmp.ops) == 0 {
+				state.debugView.SetText("No changes since " + revision + ".")
+				return
+			}
+			state.debugView.SetText(fmt.Sprintf("%d differences between %s and the working copy. Press x to export.", len(cmp.ops), revision))
+		}, nil
+	})
+}
+
+// showRevisionPicker lists the commits that touched the active file, read
+// in the background, and compares the chosen one against the working copy.
+func (state *appState) showRevisionPicker() {
+	file := state.activeFile
+	if file == "" {
+		state.debugView.SetText("Open a file with Enter to pick a revision.")
+		return
+	}
+
+	state.runLoad(loadView, "revisions of "+filepath.Base(file), func(ctx context.Context, job *loadJob) (func(), error) {
+		revisions, err := gitLog(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if len(revisions) == 0 {
+				state.debugView.SetText("No commits found for " + tview.Escape(file) + ".")
+				return
+			}
+			state.showRevisions(file, revisions)
+		}, nil
+	})
+}
+
+// showRevisions lets the user pick one of the revisions of file
+func (state *appState) showRevisions(file string, revisions []gitRevision) {
+	picker := tview.NewList()
+	picker.SetBorder(true).SetTitle("Revisions of " + tview.Escape(file))
+	for _, revision := range revisions {
+		revision := revision // capture range variable
+		picker.AddItem(revision.hash+"  "+revision.date+"  "+tview.Escape(revision.author), tview.Escape(revision.subject), 0, func() {
+			state.returnToMain()
+			state.compareWithRevision(revision.hash)
+		})
+	}
+	picker.SetDoneFunc(state.returnToMain)
+	picker.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && (event.Rune() == 'q' || event.Rune() == 'Q') {
+			state.returnToMain()
+			return nil
+		}
+		return event
+	})
+
+	state.app.SetRoot(picker, true).SetFocus(picker)
+}
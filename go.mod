@@ -3,6 +3,7 @@ module tjv
 go 1.22.6
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/gdamore/tcell/v2 v2.7.1
 	github.com/jawher/mow.cli v1.2.0
 	github.com/rivo/tview v0.0.0-20240818110301-fd649dbf1223
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// Directory in the config directory holding saved snapshots, one file per document
+	snapshotsDir = "snapshots"
+	// Files larger than this are not kept in the snapshot history
+	maxSnapshotSize = 8 << 20
+	// Snapshots of all files together are kept up to this many bytes of the
+	// files they were taken of; beyond it the oldest are dropped
+	maxSnapshotMemory = 64 << 20
+	// Height of the timeline panel, including its border
+	timelinePanelHeight = 10
+)
+
+// snapshot is a version of a document as parsed when it was opened or reloaded
+type snapshot struct {
+	number  int // counts the snapshots of a file from 1, also across saved sessions
+	taken   time.Time
+	value   interface{}
+	changes int   // differences from the snapshot before it, -1 until compared
+	size    int64 // bytes of the file, or of the saved line, it was read from
+}
+
+// snapshotCandidate is a version of a file checked against the history of
+// the file in the background, ready for recordSnapshot
+type snapshotCandidate struct {
+	value interface{}
+	size  int64
+	skip  bool       // the history is off or the file is too large for it
+	saved []snapshot // the saved history, read if the history was not known
+	last  int        // number of the snapshot value was compared with, 0 if none
+	same  bool       // value equals that snapshot
+}
+
+// savedSnapshot is one line of a file in the snapshots directory
+type savedSnapshot struct {
+	File   string      `json:"file"`
+	Number int         `json:"number,omitempty"` // missing in histories saved before numbers were
+	Taken  time.Time   `json:"taken"`
+	Value  interface{} `json:"value"`
+}
+
+// snapshotPath returns the file in the snapshots directory holding the history of file
+func snapshotPath(file string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", file, err)
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, snapshotsDir, hex.EncodeToString(sum[:8])+".jsonl"), nil
+}
+
+// loadSnapshots reads the saved history of file, keeping the newest limit snapshots
+func loadSnapshots(file string, limit int) ([]snapshot, error) {
+	path, err := snapshotPath(file)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshots: %w", err)
+	}
+	defer f.Close()
+
+	var snapshots []snapshot
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 2*maxSnapshotSize)
+	for scanner.Scan() {
+		var saved savedSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &saved); err != nil {
+			return nil, fmt.Errorf("invalid snapshot in %s: %w", path, err)
+		}
+		shot := snapshot{number: saved.Number, taken: saved.Taken, value: saved.Value, changes: -1, size: int64(len(scanner.Bytes()))}
+		if shot.number == 0 {
+			shot.number = 1
+			if n := len(snapshots); n > 0 {
+				shot.number = snapshots[n-1].number + 1
+			}
+		}
+		snapshots = append(snapshots, shot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading snapshots: %w", err)
+	}
+	if len(snapshots) <= limit {
+		return snapshots, nil
+	}
+
+	// Drop the snapshots beyond the limit from the file too, so it does not keep growing
+	snapshots = snapshots[len(snapshots)-limit:]
+	var content []byte
+	abs, _ := filepath.Abs(file)
+	for _, shot := range snapshots {
+		line, err := json.Marshal(savedSnapshot{File: abs, Number: shot.number, Taken: shot.taken, Value: shot.value})
+		if err != nil {
+			return snapshots, fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+		content = append(append(content, line...), '\n')
+	}
+	if err := writeFileAtomic(path, content); err != nil {
+		return snapshots, fmt.Errorf("failed to trim snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// saveSnapshot appends a snapshot of file to its saved history
+func saveSnapshot(file string, shot snapshot) error {
+	path, err := snapshotPath(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+	abs, _ := filepath.Abs(file)
+	line, err := json.Marshal(savedSnapshot{File: abs, Number: shot.number, Taken: shot.taken, Value: shot.value})
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshots: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return f.Close()
+}
+
+// compareSnapshots diffs two snapshots of the timeline file in the
+// background, showing the older one in the content pane and the newer one
+// in the compare pane
+func (state *appState) compareSnapshots(older, newer int) {
+	file := state.timelineFile
+	snapshots := state.snapshots[file]
+	if older > newer {
+		older, newer = newer, older
+	}
+	left, right := snapshots[older], snapshots[newer]
+	label := func(shot snapshot) string {
+		return fmt.Sprintf("%s#%d", filepath.Base(file), shot.number)
+	}
+	title := func(shot snapshot) string {
+		return fmt.Sprintf("%s (%s)", label(shot), shot.taken.Format("15:04:05"))
+	}
+	options := state.diffOptions
+
+	state.runLoad(loadCompare, label(right), func(ctx context.Context, job *loadJob) (func(), error) {
+		job.phase.Store(loadFormatting)
+		leftDocument := newFormattedDocument(left.value, nil)
+		rightDocument := newFormattedDocument(right.value, nil)
+		job.phase.Store(loadComparing)
+		cmp := &comparison{
+			leftPath:  label(left),
+			rightPath: label(right),
+			left:      left.value,
+			right:     right.value,
+			options:   options,
+			ops:       diffValues(nil, left.value, right.value, options),
+		}
+
+		return func() {
+			if snapshots := state.snapshots[file]; newer == older+1 && newer < len(snapshots) && snapshots[newer].number == right.number && snapshots[newer].changes < 0 {
+				snapshots[newer].changes = len(cmp.ops)
+				state.refreshTimeline()
+			}
+			state.setContentDocument(file, leftDocument)
+			state.fileContent.SetTitle(title(left))
+			state.showComparePane(title(right), rightDocument)
+			state.comparison = cmp
+			if len(cmp.ops) == 0 {
+				state.debugView.SetText(fmt.Sprintf("Snapshots #%d and #%d are identical.", left.number, right.number))
+				return
+			}
+			state.debugView.SetText(fmt.Sprintf("%d differences from #%d to #%d. Press x to export.", len(cmp.ops), left.number, right.number))
+		}, nil
+	})
+}
+
+// prepareSnapshot returns a func that checks a new version of file against
+// its history in the background, given the size of the file. It reads the
+// saved history first if the history of file is not known yet.
+func (state *appState) prepareSnapshot(file string) func(value interface{}, size int64) snapshotCandidate {
+	snapshots, known := state.snapshots[file]
+	var last snapshot
+	if n := len(snapshots); n > 0 {
+		last = snapshots[n-1]
+	}
+	history := state.history
+	return func(value interface{}, size int64) snapshotCandidate {
+		candidate := snapshotCandidate{value: value, size: size}
+		if history.Limit <= 0 || size > maxSnapshotSize {
+			candidate.skip = true
+			return candidate
+		}
+		if !known && history.Save {
+			saved, err := loadSnapshots(file, history.Limit)
+			if err != nil {
+				errorLogger.Printf("Failed to load snapshots of %s: %v", file, err)
+			}
+			candidate.saved = saved
+			if n := len(saved); n > 0 {
+				last = saved[n-1]
+			}
+		}
+		candidate.last = last.number
+		candidate.same = last.number > 0 && reflect.DeepEqual(last.value, value)
+		return candidate
+	}
+}
+
+// recordSnapshot adds a version of file checked by prepareSnapshot to its
+// history unless it is the same as the last one, dropping the oldest beyond
+// the limit and, across all files, beyond maxSnapshotMemory
+func (state *appState) recordSnapshot(file string, candidate snapshotCandidate) {
+	if candidate.skip {
+		return
+	}
+	snapshots, ok := state.snapshots[file]
+	if !ok {
+		snapshots = candidate.saved
+	}
+	n := len(snapshots)
+	if n > 0 && snapshots[n-1].number == candidate.last && candidate.same {
+		state.snapshots[file] = snapshots
+		return
+	}
+
+	// The history may have changed or been dropped since the candidate was checked
+	shot := snapshot{number: candidate.last + 1, taken: time.Now(), value: candidate.value, changes: -1, size: candidate.size}
+	if n > 0 && snapshots[n-1].number >= shot.number {
+		shot.number = snapshots[n-1].number + 1
+	}
+	snapshots = append(snapshots, shot)
+	if len(snapshots) > state.history.Limit {
+		snapshots = snapshots[len(snapshots)-state.history.Limit:]
+		if state.timelineFile == file {
+			state.timelineMark = -1 // The indices moved
+		}
+	}
+	state.snapshots[file] = snapshots
+	state.trimSnapshots(file)
+
+	if state.history.Save {
+		if err := saveSnapshot(file, shot); err != nil {
+			errorLogger.Printf("Failed to save snapshot of %s: %v", file, err)
+		}
+	}
+	if state.timelineVisible && state.timelineFile == file {
+		state.refreshTimeline()
+	}
+}
+
+// refreshTimeline lists the snapshots of the timeline file, oldest first,
+// keeping the selection
+func (state *appState) refreshTimeline() {
+	current := state.timeline.GetCurrentItem()
+	state.timelineRefreshing = true // Rebuilding the list moves its selection
+	defer func() { state.timelineRefreshing = false }()
+	state.timeline.Clear()
+
+	snapshots := state.snapshots[state.timelineFile]
+	for i, shot := range snapshots {
+		marker := "  "
+		if i == state.timelineMark {
+			marker = "[yellow]*[-] "
+		}
+		text := fmt.Sprintf("%s#%-4d %s", marker, shot.number, shot.taken.Format("2006-01-02 15:04:05"))
+		switch {
+		case i == 0:
+		case shot.changes < 0:
+			text += "  [gray]changed[-]"
+		default:
+			text += fmt.Sprintf("  [gray]%d differences[-]", shot.changes)
+		}
+		state.timeline.AddItem(text, "", 0, nil)
+	}
+	if current >= 0 && current < len(snapshots) {
+		state.timeline.SetCurrentItem(current)
+	}
+	state.timeline.SetTitle(fmt.Sprintf("Snapshots of %s: %d (Up/Down: scrub, Space: mark, Enter: diff, Esc: close)", tview.Escape(filepath.Base(state.timelineFile)), len(snapshots)))
+}
+
+// setupTimeline builds the timeline panel shown below the content by 'T'.
+// Moving through it diffs the selected snapshot against the marked one, or
+// against the snapshot before it when none is marked.
+func (state *appState) setupTimeline() {
+	state.timeline = tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true).SetWrapAround(false)
+	state.timeline.SetBorder(true)
+
+	scrub := func(index int) {
+		if !state.timelineVisible || state.timelineRefreshing {
+			return
+		}
+		other := index - 1
+		if state.timelineMark >= 0 && state.timelineMark != index {
+			other = state.timelineMark
+		}
+		if other < 0 || index >= len(state.snapshots[state.timelineFile]) {
+			state.debugView.SetText("The first snapshot has nothing before it to compare with.")
+			return
+		}
+		state.compareSnapshots(other, index)
+	}
+	state.timeline.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		scrub(index)
+	})
+	state.timeline.SetSelectedFunc(func(index int, _ string, _ string, _ rune) {
+		scrub(index)
+	})
+	state.timeline.SetDoneFunc(state.toggleTimeline)
+	state.timeline.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case ' ':
+			index := state.timeline.GetCurrentItem()
+			if state.timelineMark == index {
+				state.timelineMark = -1
+			} else {
+				state.timelineMark = index
+			}
+			state.refreshTimeline()
+			return nil
+		case 'T', 'q':
+			state.toggleTimeline()
+			return nil
+		}
+		return event
+	})
+}
+
+// toggleTimeline shows or hides the snapshots of the open file. Closing it
+// shows the latest version of the file again.
+func (state *appState) toggleTimeline() {
+	if state.timelineVisible {
+		state.timelineVisible = false
+		state.arrangeRows()
+		state.cancelLoad(loadCompare)
+		if snapshots := state.snapshots[state.timelineFile]; len(snapshots) > 0 {
+			state.setContentValue(state.timelineFile, snapshots[len(snapshots)-1].value)
+			state.fileContent.SetTitle(filepath.Base(state.timelineFile))
+		}
+		state.focusContent()
+		return
+	}
+
+	file := state.activeFile
+	if file == "" || state.follow != nil {
+		state.debugView.SetText("Open a file with Enter to see its snapshots.")
+		return
+	}
+	if state.blockedByEdits() {
+		return
+	}
+	snapshots := state.snapshots[file]
+	switch {
+	case len(snapshots) == 0 && state.history.Limit <= 0:
+		state.debugView.SetText(`Snapshots are turned off: set "limit" under "history" in config.json to keep them.`)
+		return
+	case len(snapshots) == 0:
+		state.debugView.SetText(fmt.Sprintf("No snapshots are kept of %s: it is larger than %s.", filepath.Base(file), formatSize(maxSnapshotSize)))
+		return
+	case len(snapshots) == 1:
+		state.debugView.SetText(fmt.Sprintf("%s has not changed since it was opened. A snapshot is taken each time it is reloaded with changes.", filepath.Base(file)))
+		return
+	}
+
+	state.timelineFile = file
+	state.timelineMark = -1
+	state.timelineVisible = true
+	state.arrangeRows()
+	state.refreshTimeline()
+	state.timeline.SetCurrentItem(len(snapshots) - 1)
+	state.app.SetFocus(state.timeline)
+	state.compareSnapshots(len(snapshots)-2, len(snapshots)-1)
+}
+
+// trimSnapshots drops the oldest snapshots of all files until they fit in
+// maxSnapshotMemory, keeping the newest snapshot of recorded. A file whose
+// snapshots are all dropped has its history read again when next opened.
+func (state *appState) trimSnapshots(recorded string) {
+	var total int64
+	for _, snapshots := range state.snapshots {
+		for _, shot := range snapshots {
+			total += shot.size
+		}
+	}
+	for total > maxSnapshotMemory {
+		oldest := ""
+		for file, snapshots := range state.snapshots {
+			if len(snapshots) == 0 || (file == recorded && len(snapshots) == 1) {
+				continue
+			}
+			if oldest == "" || snapshots[0].taken.Before(state.snapshots[oldest][0].taken) {
+				oldest = file
+			}
+		}
+		if oldest == "" {
+			return
+		}
+
+		snapshots := state.snapshots[oldest]
+		total -= snapshots[0].size
+		if len(snapshots) == 1 {
+			delete(state.snapshots, oldest)
+		} else {
+			state.snapshots[oldest] = snapshots[1:]
+		}
+		if oldest == state.timelineFile {
+			state.timelineMark = -1 // The indices moved
+			if state.timelineVisible && oldest != recorded {
+				state.refreshTimeline()
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadSnapshotsKeepsNumbers(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	file := filepath.Join(t.TempDir(), "a.json")
+	for i := 1; i <= 5; i++ {
+		if err := saveSnapshot(file, snapshot{number: i, taken: time.Now(), value: float64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	numbers := func(snapshots []snapshot) []int {
+		var result []int
+		for _, shot := range snapshots {
+			result = append(result, shot.number)
+		}
+		return result
+	}
+	// Trimming to the limit drops the oldest, and the trimmed file keeps the numbers
+	for _, limit := range []int{3, 10} {
+		snapshots, err := loadSnapshots(file, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := numbers(snapshots), []int{3, 4, 5}; !reflect.DeepEqual(got, want) {
+			t.Errorf("limit %d: numbers = %v, want %v", limit, got, want)
+		}
+	}
+}
+
+func TestRecordSnapshot(t *testing.T) {
+	state := &appState{history: historySettings{Limit: 10}, snapshots: map[string][]snapshot{}}
+	record := func(file string, value interface{}, size int64) {
+		state.recordSnapshot(file, state.prepareSnapshot(file)(value, size))
+	}
+	numbers := func(file string) []int {
+		var result []int
+		for _, shot := range state.snapshots[file] {
+			result = append(result, shot.number)
+		}
+		return result
+	}
+
+	// Versions equal to the last snapshot are not recorded
+	record("a.json", map[string]interface{}{"a": 1.0}, 10)
+	record("a.json", map[string]interface{}{"a": 1.0}, 10)
+	record("a.json", map[string]interface{}{"a": 2.0}, 10)
+	if got, want := numbers("a.json"), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers = %v, want %v", got, want)
+	}
+
+	// Beyond the memory limit the oldest snapshots of any file are dropped
+	for i := 0; i <= maxSnapshotMemory/maxSnapshotSize; i++ {
+		record(fmt.Sprintf("big%d.json", i), float64(i), maxSnapshotSize)
+	}
+	if _, ok := state.snapshots["a.json"]; ok {
+		t.Errorf("a.json kept %v, want its snapshots dropped", numbers("a.json"))
+	}
+	if _, ok := state.snapshots["big0.json"]; ok {
+		t.Error("big0.json kept its snapshot, want it dropped")
+	}
+	for i := 1; i <= maxSnapshotMemory/maxSnapshotSize; i++ {
+		if file := fmt.Sprintf("big%d.json", i); len(state.snapshots[file]) != 1 {
+			t.Errorf("%s numbers = %v, want [1]", file, numbers(file))
+		}
+	}
+}
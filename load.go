@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// Slots of appState.loads; a load cancels the one before it in its slot.
+// loadView is for the data of full-screen views such as the drift matrix, and
+// loadWatch for reloads of the open file after it changed on disk, so that
+// they never cancel a file the user is opening.
+const (
+	loadMain = iota
+	loadCompare
+	loadView
+	loadWatch
+)
+
+// Phases of a load, as shown next to the spinner
+const (
+	loadReading int32 = iota
+	loadParsing
+	loadFormatting
+	loadComparing
+)
+
+var loadPhaseNames = [...]string{
+	loadReading:    "reading",
+	loadParsing:    "parsing",
+	loadFormatting: "formatting",
+	loadComparing:  "comparing",
+}
+
+// Frames of the spinner shown in the status row while loading
+var spinnerFrames = []rune("⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏")
+
+// How often the spinner and progress of a load are redrawn
+const spinnerInterval = 100 * time.Millisecond
+
+// loadJob is a load running in the background. Its progress is updated by
+// the loading goroutine and read by the spinner.
+type loadJob struct {
+	label  string
+	read   atomic.Int64
+	size   atomic.Int64
+	phase  atomic.Int32
+	cancel context.CancelFunc
+	shown  bool // the spinner was drawn; only used on the UI goroutine
+}
+
+// progressReader counts the bytes read through it and stops once its
+// context is cancelled
+type progressReader struct {
+	ctx    context.Context
+	reader io.Reader
+	read   *atomic.Int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.reader.Read(p)
+	r.read.Add(int64(n))
+	return n, err
+}
+
+// readFile reads and parses a document like readJSONFile, reporting progress
+// and giving up once ctx is cancelled
+func (job *loadJob) readFile(ctx context.Context, file string) (interface{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		job.size.Store(info.Size())
+	}
+
+	content, err := io.ReadAll(&progressReader{ctx: ctx, reader: f, read: &job.read})
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	job.phase.Store(loadParsing)
+	return parseDocument(file, content)
+}
+
+// status describes the progress of the job for the status row
+func (job *loadJob) status(frame int) string {
+	status := fmt.Sprintf("%c Loading %s: %s", spinnerFrames[frame%len(spinnerFrames)], tview.Escape(job.label), loadPhaseNames[job.phase.Load()])
+	if size := job.size.Load(); size > 0 && job.phase.Load() == loadReading {
+		status += fmt.Sprintf(" %d%% (%s of %s)", job.read.Load()*100/size, formatSize(job.read.Load()), formatSize(size))
+	}
+	return status + "…"
+}
+
+// cancelLoad stops the load running in a slot, dropping its result
+func (state *appState) cancelLoad(slot int) {
+	if job := state.loads[slot]; job != nil {
+		job.cancel()
+		state.loads[slot] = nil
+		if job.shown {
+			state.debugView.SetText("")
+		}
+	}
+}
+
+// runLoad runs load in the background, showing a spinner with its progress
+// in the status row while it takes. Starting another load in the same slot
+// cancels it and the results of a superseded load are dropped. The func load
+// returns is run on the UI goroutine to show the result.
+func (state *appState) runLoad(slot int, label string, load func(ctx context.Context, job *loadJob) (func(), error)) {
+	state.cancelLoad(slot)
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &loadJob{label: label, cancel: cancel}
+	state.loads[slot] = job
+
+	go func() {
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			state.app.QueueUpdateDraw(func() {
+				if state.loads[slot] == job {
+					job.shown = true
+					state.debugView.SetText(job.status(frame))
+				}
+			})
+		}
+	}()
+
+	go func() {
+		apply, err := load(ctx, job)
+		cancel() // Stops the spinner
+		state.app.QueueUpdateDraw(func() {
+			if state.loads[slot] != job {
+				return
+			}
+			state.loads[slot] = nil
+			if job.shown {
+				state.debugView.SetText("")
+			}
+			if err != nil {
+				errorLogger.Printf("Failed to load %s: %v", label, err)
+				state.debugView.SetText("[red]Failed to load " + tview.Escape(label) + ": " + tview.Escape(err.Error()) + "[-]")
+				return
+			}
+			apply()
+		})
+	}()
+}
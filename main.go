@@ -3,22 +3,30 @@ package main
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
+	cli "github.com/jawher/mow.cli"
 	"github.com/rivo/tview"
 )
 
+const (
+	// Prefix of files marked for multi-file views such as the drift matrix
+	fileMarker = "● "
+	// Colour tag around the active entry of the file list
+	activeFileColor = "[lightgreen]"
+	// How often a running directory walk adds the files found to the list
+	walkFlushInterval = 100 * time.Millisecond
+)
+
 var (
 	// Regular expressions to match JSON elements
 	keyRegex         = regexp.MustCompile(`"([^"]+)":\s*`)
@@ -29,6 +37,10 @@ var (
 	booleanRegex     = regexp.MustCompile(`\b(true|false)\b`)
 	nullRegex        = regexp.MustCompile(`:\s*(null)`)
 
+	// Tags written by colorizeJSON and highlightSearchResult, used to map
+	// search matches in the plain text back into the coloured text
+	contentTagRegex = regexp.MustCompile(`\[(blue|lightgreen|green|yellow|lightblue|red|-|:olive|:-)\]|\["[^"\]]*"\]`)
+
 	infoLogger  *log.Logger
 	errorLogger *log.Logger
 )
@@ -38,6 +50,14 @@ type searchOptions struct {
 	useRegex      bool
 }
 
+// searchResult is a match on one line of a content pane, as byte offsets
+// into the plain text of the line, and the JSON path of the value on that line
+type searchResult struct {
+	pane             int
+	line, start, end int
+	path             jsonPath
+}
+
 type appState struct {
 	app               *tview.Application
 	fileList          *tview.List
@@ -45,46 +65,159 @@ type appState struct {
 	secondFileContent *tview.TextView
 	debugView         *tview.TextView
 	footer            *tview.TextView
+	searchInput       *tview.InputField
+	searchInfo        *tview.TextView
+	statusRow         *tview.Pages
 	mainFlex          *tview.Flex
 	rootLayout        *tview.Flex
 
 	actionFuncs        []func()
+	files              []string
+	allFiles           []string
+	fileFilter         string
+	fileRows           []fileRow
+	fileRowIndex       map[string]int // row of each path in fileRows
+	rootDir            string
+	expandedDirs       map[string]bool
+	fileFilterInput    *tview.InputField
+	filePanel          *tview.Flex
+	markedFiles        map[string]bool
+	activeFile         string
+	compareFile        string
+	comparison         *comparison
+	diffOptions        diffOptions
+	discovery          discoveryOptions
+	walkFailures       []walkFailure
+	walking            bool
+	walkGeneration     int
+	cancelWalk         context.CancelFunc
+	validations        map[string]fileValidation
+	columns            []string
+	sortOrder          fileSortOrder
+	follow             *followState
+	loads              [4]*loadJob
+	history            historySettings
+	snapshots          map[string][]snapshot
+	timeline           *tview.List
+	timelineVisible    bool
+	timelineFile       string
+	timelineMark       int // index of the snapshot marked with Space, or -1
+	timelineRefreshing bool
+	tabs               []documentTab
+	edits              [][]replacement // replacements shown in the open document but not written yet
+	quitWarned         bool
+	currentTab         int
+	tabBar             *tview.TextView
+	tabBarVisible      bool
+	fileNameWidth      int
+	pairs              []filePair
+	leftRoot           string
+	rightRoot          string
 	isFileListFocused  bool
 	scrollOffset       int
 	activeFileIndex    int
 	layoutHorizontal   bool
 	secondFileVisible  bool
 	searchString       string
-	searchResults      []struct{ line, pos int }
+	searchResults      []searchResult
+	panes              [2]contentPane
+	searchPane         int
+	searchBothPanes    bool
+	filterView         bool
 	currentSearchIndex int
 	searchMode         bool
+	searchTimer        *time.Timer // runs the search once typing in the prompt pauses
+	searchOptions      searchOptions
+	searchHistory      []string
+	historyIndex       int
+	searchDraft        string
+	fileMatches        []fileMatch
+	matchPanel         *tview.Flex
+	matchFilter        *tview.InputField
+	matchList          *tview.List
+	matchIndices       []int
+	matchListVisible   bool
+	fileSearchString   string
 }
 
 func main() {
-	initLoggers()
-
-	state := initializeApp()
-	setupLayout(state)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	reloadJSONFiles(ctx, state.fileList, state.fileContent, state.debugView, ".", &state.actionFuncs, &state.activeFileIndex)
-
-	state.setupKeyBindings()
-
-	if err := state.app.SetRoot(state.rootLayout, true).Run(); err != nil {
-		errorLogger.Printf("Application error: %v", err)
-		panic(err)
+	app := cli.App("tjv", "Terminal JSON viewer")
+	app.Spec = "[OPTIONS]"
+
+	ignore := app.StringsOpt("i ignore", nil, "Path pattern to leave out of comparisons, e.g. **.timestamp (repeatable)")
+	arrayMatch := app.StringOpt("a array-match", "index", "How array elements are paired when comparing: index, lcs or key=FIELD")
+
+	// Discovery flags override config.json only when given
+	var extSet, includeSet, excludeSet, maxDepthSet, hiddenSet, noGitignoreSet, followSymlinksSet bool
+	extensions := app.Strings(cli.StringsOpt{Name: "e ext", Desc: "File extension to list, e.g. .geojson or .json.gz (repeatable, default .json)", SetByUser: &extSet})
+	include := app.Strings(cli.StringsOpt{Name: "include", Desc: "Only list files matching this glob, e.g. configs/**/*.json (repeatable)", SetByUser: &includeSet})
+	exclude := app.Strings(cli.StringsOpt{Name: "x exclude", Desc: "Skip files and directories matching this glob (repeatable, default node_modules and vendor)", SetByUser: &excludeSet})
+	maxDepth := app.Int(cli.IntOpt{Name: "max-depth", Desc: "Directory levels to descend below the root, 0 for no limit", SetByUser: &maxDepthSet})
+	hidden := app.Bool(cli.BoolOpt{Name: "hidden", Desc: "List files and directories whose names start with a dot", SetByUser: &hiddenSet})
+	noGitignore := app.Bool(cli.BoolOpt{Name: "no-gitignore", Desc: "List files that .gitignore files ignore", SetByUser: &noGitignoreSet})
+	followSymlinks := app.Bool(cli.BoolOpt{Name: "L follow-symlinks", Desc: "Descend into symlinked directories", SetByUser: &followSymlinksSet})
+
+	config := func() settings {
+		loaded, err := loadSettings()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tjv:", err)
+			cli.Exit(2)
+		}
+		options := &loaded.Discovery
+		if extSet {
+			options.Extensions = *extensions
+		}
+		if includeSet {
+			options.Include = *include
+		}
+		if excludeSet {
+			options.Exclude = *exclude
+		}
+		if maxDepthSet {
+			options.MaxDepth = *maxDepth
+		}
+		if hiddenSet {
+			options.Hidden = *hidden
+		}
+		if noGitignoreSet {
+			options.Gitignore = !*noGitignore
+		}
+		if followSymlinksSet {
+			options.FollowSymlinks = *followSymlinks
+		}
+		return loaded
 	}
-}
 
-func containsAnyPosition(start, end int, positions []int) bool {
-	for _, pos := range positions {
-		if pos >= start && pos < end {
-			return true
+	app.Action = func() {
+		options, err := newDiffOptions(*ignore, *arrayMatch)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tjv:", err)
+			cli.Exit(2)
 		}
+		runViewer(options, config(), "", "")
 	}
-	return false
+	app.Command("diff", "Compare two JSON documents and report the differences", diffCommand)
+	app.Command("compare-dirs", "Browse the differences between two directory trees", func(cmd *cli.Cmd) {
+		cmd.Spec = "LEFT RIGHT"
+		leftRoot := cmd.StringArg("LEFT", "", "First directory")
+		rightRoot := cmd.StringArg("RIGHT", "", "Second directory")
+		cmd.Action = func() {
+			options, err := newDiffOptions(*ignore, *arrayMatch)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "tjv:", err)
+				cli.Exit(2)
+			}
+			for _, root := range []string{*leftRoot, *rightRoot} {
+				if info, err := os.Stat(root); err != nil || !info.IsDir() {
+					fmt.Fprintf(os.Stderr, "tjv: %s is not a directory\n", root)
+					cli.Exit(2)
+				}
+			}
+			runViewer(options, config(), *leftRoot, *rightRoot)
+		}
+	})
+
+	app.Run(os.Args)
 }
 
 func colorizeJSON(input string) string {
@@ -141,6 +274,22 @@ func colorizeJSON(input string) string {
 	return input
 }
 
+// compileSearch turns a search string into a regular expression honouring the search options
+func compileSearch(searchString string, options searchOptions) (*regexp.Regexp, error) {
+	pattern := searchString
+	if !options.useRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if !options.caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern: %w", err)
+	}
+	return re, nil
+}
+
 func executeCriticalOperation(debugView *tview.TextView, operation func() error) {
 	defer recoverFromPanic(debugView)
 
@@ -177,35 +326,64 @@ func handleScroll(event *tcell.EventKey, firstContent, secondContent *tview.Text
 	secondContent.ScrollTo(*scrollOffset, 0)
 }
 
-func highlightSearchResult(fileContent *tview.TextView, searchPositions []int, searchString string) {
-	if len(searchPositions) == 0 {
-		return
+// highlightLine marks the results of one line; ids holds the region number of each result
+func highlightLine(line string, results []searchResult, ids []int) string {
+	tags := contentTagRegex.FindAllStringIndex(line, -1)
+
+	var out strings.Builder
+	current := 0  // next result to open or close
+	open := false // whether results[current] is open
+	plain := 0    // offset into the line with tags removed
+	for i := 0; ; {
+		if open && results[current].end == plain {
+			out.WriteString(`[:-][""]`)
+			open = false
+			current++
+		}
+		if !open && current < len(results) && results[current].start == plain {
+			fmt.Fprintf(&out, `["%s"][:olive]`, searchRegion(ids[current]))
+			open = true
+		}
+		if i >= len(line) {
+			break
+		}
+		if len(tags) > 0 && tags[0][0] == i {
+			out.WriteString(line[tags[0][0]:tags[0][1]])
+			i = tags[0][1]
+			tags = tags[1:]
+			continue
+		}
+		out.WriteByte(line[i])
+		i++
+		plain++
 	}
+	if open {
+		out.WriteString(`[:-][""]`)
+	}
+	return out.String()
+}
 
-	content := fileContent.GetText(true)
-	lines := strings.Split(content, "\n")
-
-	var highlightedContent strings.Builder
-	currentPosition := 0
-
-	for i, line := range lines {
-		lineStart := currentPosition
-		lineEnd := lineStart + len(line)
+// highlightSearchResult marks every search result in the coloured text with a
+// background colour and a region named after its ID, so that the current
+// result can be highlighted by region ID. Results must be in line order.
+func highlightSearchResult(coloredText string, results []searchResult, ids []int) string {
+	if len(results) == 0 {
+		return coloredText
+	}
 
-		if containsAnyPosition(lineStart, lineEnd, searchPositions) {
-			highlightedContent.WriteString("[::b]" + line + "[::-]") // Use bold text as highlight
-		} else {
-			highlightedContent.WriteString(line)
+	lines := strings.Split(coloredText, "\n")
+	for first := 0; first < len(results); {
+		lineIndex := results[first].line
+		last := first
+		for last < len(results) && results[last].line == lineIndex {
+			last++
 		}
-
-		if i < len(lines)-1 {
-			highlightedContent.WriteString("\n")
+		if lineIndex < len(lines) {
+			lines[lineIndex] = highlightLine(lines[lineIndex], results[first:last], ids[first:last])
 		}
-
-		currentPosition = lineEnd + 1 // +1 for the newline character
+		first = last
 	}
-
-	fileContent.SetText(highlightedContent.String())
+	return strings.Join(lines, "\n")
 }
 
 func initializeApp() *appState {
@@ -213,20 +391,32 @@ func initializeApp() *appState {
 		app:               tview.NewApplication(),
 		isFileListFocused: true,
 		layoutHorizontal:  true,
+		markedFiles:       map[string]bool{},
+		expandedDirs:      map[string]bool{},
+		snapshots:         map[string][]snapshot{},
+		tabs:              []documentTab{{}},
+		rootDir:           ".",
+		searchOptions:     searchOptions{caseSensitive: true},
 	}
 
 	state.fileList = tview.NewList().ShowSecondaryText(false)
 	state.fileList.SetBorder(true).SetBorderColor(tcell.ColorGreen).SetTitle("Files")
 
-	state.fileContent = tview.NewTextView().SetDynamicColors(true).SetWrap(true).SetScrollable(true)
+	state.fileContent = tview.NewTextView().SetDynamicColors(true).SetRegions(true).SetWrap(true).SetScrollable(true)
 	state.fileContent.SetBorder(true).SetBorderColor(tcell.ColorGray).SetTitle("Content")
+	state.panes[mainPane].view = state.fileContent
 
 	state.debugView = tview.NewTextView().SetDynamicColors(true).SetWrap(true)
 	state.debugView.SetText("Press F1, ?, or h for help. Press q to quit.")
 
+	state.tabBar = tview.NewTextView().SetDynamicColors(true)
+
 	state.footer = tview.NewTextView().SetText("F1/?/h - Help, qQ - Quit, / - Search")
 	state.footer.SetDynamicColors(true).SetTextAlign(tview.AlignCenter)
 
+	state.searchInput = tview.NewInputField().SetFieldBackgroundColor(tcell.ColorDefault)
+	state.searchInfo = tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignRight)
+
 	return state
 }
 
@@ -245,95 +435,34 @@ func initLoggers() {
 	errorLogger = log.New(errorFile, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
 }
 
-func loadFileContentAsync(app *tview.Application, file string, content *tview.TextView, debugView *tview.TextView, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	fileContent, err := readFileContent(file)
-	if err != nil {
-		app.QueueUpdateDraw(func() {
-			errorLogger.Printf("Failed to read file %s: %v", file, err)
-			debugView.SetText("[red]Failed to read file. Check error log for details.[-]")
-		})
-		return
-	}
-
-	var formattedContent map[string]interface{}
-	if err := json.Unmarshal([]byte(fileContent), &formattedContent); err != nil {
-		app.QueueUpdateDraw(func() {
-			errorLogger.Printf("Invalid JSON in file %s: %v", file, err)
-			debugView.SetText("[red]Invalid JSON. Check error log for details.[-]")
-		})
-		return
-	}
-
-	prettyContent, _ := json.MarshalIndent(formattedContent, "", "  ")
-	coloredContent := colorizeJSON(string(prettyContent))
-
-	app.QueueUpdateDraw(func() {
-		content.SetText(coloredContent).SetTitle(filepath.Base(file))
-	})
-}
-
-func loadJSONFilesWithContext(ctx context.Context, dir string) ([]string, error) {
+// loadJSONFilesWithContext lists the documents under dir that the discovery
+// options select, together with the paths that could not be read
+func loadJSONFilesWithContext(ctx context.Context, dir string, options discoveryOptions) ([]string, []walkFailure, error) {
 	var files []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err != nil {
-				return fmt.Errorf("error accessing path %s: %w", path, err)
-			}
-			if !info.IsDir() && filepath.Ext(info.Name()) == ".json" {
-				files = append(files, path)
-			}
-			return nil
+	var failures []walkFailure
+	err := walkDocuments(ctx, dir, options, func(path string, isDir bool) {
+		if !isDir {
+			files = append(files, path)
 		}
+	}, func(failure walkFailure) {
+		failures = append(failures, failure)
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error walking directory %s: %w", dir, err)
+		return files, failures, fmt.Errorf("error walking directory %s: %w", dir, err)
 	}
-	return files, nil
+	return files, failures, nil
 }
 
-func performSearch(content, searchString string, options searchOptions) []int {
-	var positions []int
-
-	if options.useRegex {
-		var re *regexp.Regexp
-		var err error
-		if options.caseSensitive {
-			re, err = regexp.Compile(searchString)
-		} else {
-			re, err = regexp.Compile("(?i)" + searchString)
-		}
-		if err != nil {
-			errorLogger.Printf("Invalid regex pattern: %v", err)
-			return positions
-		}
-
-		matches := re.FindAllStringIndex(content, -1)
-		for _, match := range matches {
-			positions = append(positions, match[0])
-		}
-	} else {
-		if !options.caseSensitive {
-			content = strings.ToLower(content)
-			searchString = strings.ToLower(searchString)
-		}
-
-		pos := 0
-		for {
-			index := strings.Index(content[pos:], searchString)
-			if index == -1 {
-				break
-			}
-			positions = append(positions, pos+index)
-			pos += index + len(searchString)
+// findMatches returns the [start, end) byte ranges of every non-empty match
+// of a compiled search (see compileSearch) in content
+func findMatches(content string, re *regexp.Regexp) [][]int {
+	var matches [][]int
+	for _, match := range re.FindAllStringIndex(content, -1) {
+		if match[1] > match[0] {
+			matches = append(matches, match)
 		}
 	}
-
-	return positions
+	return matches
 }
 
 func recoverFromPanic(debugView *tview.TextView) {
@@ -375,65 +504,87 @@ func readFileContent(filePath string) (string, error) {
 	return content.String(), nil
 }
 
-// reloadJSONFiles loads the list of JSON files in the specified directory and updates the UI.
-func reloadJSONFiles(ctx context.Context, fileList *tview.List, fileContent, debugView *tview.TextView, dir string, actionFuncs *[]func(), activeFileIndex *int) {
-	fileList.Clear()
-	*actionFuncs = nil // Reset action functions slice
-
-	jsonFiles, err := loadJSONFilesWithContext(ctx, dir)
+// readJSONFile reads and parses a document of any top-level type. Gzipped
+// and JSON Lines files are decoded by their extension.
+func readJSONFile(filePath string) (interface{}, error) {
+	content, err := readFileContent(filePath)
 	if err != nil {
-		errorLogger.Printf("Failed to load JSON files: %v", err)
-		debugView.SetText("[red]Failed to load JSON files. Check error log for details.[-]")
-		return
+		return nil, err
 	}
+	return parseDocument(filePath, []byte(content))
+}
 
-	// Populate the list with JSON files
-	for i, file := range jsonFiles {
-		file := file // capture range variable
-		fileIndex := i
-		action := func() {
-			content, err := readFileContent(file)
-			if err != nil {
-				errorLogger.Printf("Failed to read file %s: %v", file, err)
-				debugView.SetText("[red]Failed to read file. Check error log for details.[-]")
-				return
-			}
+// runViewer starts the interactive viewer on the current directory, or on
+// the pairs of two directories when leftRoot and rightRoot are set. The
+// settings select the files listed and how the file list shows them.
+func runViewer(options diffOptions, config settings, leftRoot, rightRoot string) {
+	initLoggers()
 
-			var formattedContent map[string]interface{}
-			if err := json.Unmarshal([]byte(content), &formattedContent); err != nil {
-				errorLogger.Printf("Invalid JSON in file %s: %v", file, err)
-				debugView.SetText("[red]Invalid JSON. Check error log for details.[-]")
-				return
-			}
+	state := initializeApp()
+	state.diffOptions = options
+	state.discovery = config.Discovery
+	state.columns = config.FileList.Columns
+	state.sortOrder = parseSortOrder(config.FileList.Sort)
+	state.history = config.History
+	state.leftRoot = leftRoot
+	state.rightRoot = rightRoot
+	setupLayout(state)
 
-			prettyContent, _ := json.MarshalIndent(formattedContent, "", "  ")
-			coloredContent := colorizeJSON(string(prettyContent))
+	state.reloadFiles()
 
-			fileContent.SetText(coloredContent).SetTitle(filepath.Base(file))
+	state.setupKeyBindings()
+	state.setupSearchInput()
+	state.setupMatchList()
+	state.setupFileFilter()
+	state.setupTimeline()
 
-			*activeFileIndex = fileIndex
-			updateActiveFileHighlight(fileList, *activeFileIndex)
-		}
-		fileList.AddItem(file, "", 0, action)
-		*actionFuncs = append(*actionFuncs, action)
+	if err := state.app.EnablePaste(true).SetRoot(state.rootLayout, true).Run(); err != nil {
+		errorLogger.Printf("Application error: %v", err)
+		panic(err)
 	}
-
-	infoLogger.Println("JSON files loaded successfully")
-	debugView.SetText("Select a file to view its content.")
 }
 
 func setupLayout(state *appState) {
+	// The file panel holds the file list and, while finding a file, the fuzzy filter above it
+	state.filePanel = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(state.fileList, 0, 1, true)
 	state.mainFlex = tview.NewFlex().
-		AddItem(state.fileList, 0, 1, true).
+		AddItem(state.filePanel, 0, 1, true).
 		AddItem(state.fileContent, 0, 2, false)
 
+	// The status row shows messages, or the search prompt while searching
+	state.statusRow = tview.NewPages().
+		AddPage("status", state.debugView, true, true).
+		AddPage("search", tview.NewFlex().
+			AddItem(state.searchInput, 0, 2, true).
+			AddItem(state.searchInfo, 0, 1, false), true, false)
+
 	state.rootLayout = tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(state.mainFlex, 0, 1, true).
-		AddItem(state.debugView, 1, 1, false).
+		AddItem(state.statusRow, 1, 1, false).
+		AddItem(state.footer, 1, 1, false)
+}
+
+// arrangeRows lays out the main panes with the tab bar above them and the
+// panels open below them
+func (state *appState) arrangeRows() {
+	state.rootLayout.Clear()
+	if state.tabBarVisible {
+		state.rootLayout.AddItem(state.tabBar, 1, 0, false)
+	}
+	state.rootLayout.AddItem(state.mainFlex, 0, 1, true)
+	if state.matchListVisible {
+		state.rootLayout.AddItem(state.matchPanel, matchPanelHeight, 0, false)
+	}
+	if state.timelineVisible {
+		state.rootLayout.AddItem(state.timeline, timelinePanelHeight, 0, false)
+	}
+	state.rootLayout.
+		AddItem(state.statusRow, 1, 1, false).
 		AddItem(state.footer, 1, 1, false)
 }
 
-func toggleCompareView(app *tview.Application, firstContent *tview.TextView, secondContent **tview.TextView, secondVisible *bool, mainFlex *tview.Flex, fileList *tview.List, actionFuncs []func(), debugView *tview.TextView) {
+func toggleCompareView(app *tview.Application, firstContent *tview.TextView, secondContent **tview.TextView, secondVisible *bool, mainFlex *tview.Flex, fileList *tview.List, files []string, compareFile *string) {
 	// Get the index of the selected file
 	selectedFileIndex := fileList.GetCurrentItem()
 
@@ -446,68 +597,48 @@ func toggleCompareView(app *tview.Application, firstContent *tview.TextView, sec
 		// Show the second panel
 		if *secondContent == nil {
 			// Creating second content view
-			*secondContent = tview.NewTextView().SetDynamicColors(true).SetWrap(true).SetScrollable(true)
+			*secondContent = tview.NewTextView().SetDynamicColors(true).SetRegions(true).SetWrap(true).SetScrollable(true)
 			(*secondContent).SetBorder(true).SetBorderColor(tcell.ColorGray)
 		}
 
-		// Load the content of the selected file into the second panel without affecting the main content pane
-		mainText, _ := fileList.GetItemText(selectedFileIndex) // Get the main text of the selected item
-
-		content, err := readFileContent(filepath.Join(".", mainText))
-		if err != nil {
-			errorLogger.Printf("Failed to read file %s: %v", mainText, err)
-			debugView.SetText("[red]Failed to read file. Check error log for details.[-]")
-			return
-		}
-
-		var formattedContent map[string]interface{}
-		if err := json.Unmarshal([]byte(content), &formattedContent); err != nil {
-			errorLogger.Printf("Invalid JSON in file %s: %v", mainText, err)
-			debugView.SetText("[red]Invalid JSON. Check error log for details.[-]")
+		// The compare pane loads the selected file without affecting the main content pane
+		if selectedFileIndex < 0 || selectedFileIndex >= len(files) || files[selectedFileIndex] == "" {
 			return
 		}
-
-		prettyContent, _ := json.MarshalIndent(formattedContent, "", "  ")
-		coloredContent := colorizeJSON(string(prettyContent)) // Apply color to both keys and values
-
-		(*secondContent).SetText(coloredContent)
+		mainText := files[selectedFileIndex]
 
 		// Update the title to the filename
-		(*secondContent).SetTitle(mainText)
+		(*secondContent).SetText("").SetTitle(mainText)
 
 		// Adding second panel to layout
 		mainFlex.AddItem(*secondContent, 0, 2, false)
+		*compareFile = mainText
 		*secondVisible = true
 	}
 }
 
 // updateActiveFileHighlight updates the color of the active file in the file list
 func updateActiveFileHighlight(fileList *tview.List, activeFileIndex int) {
-	// Regular expression to match color codes in square brackets
-	re := regexp.MustCompile(`\[[^\]]*\]`)
-
-	for i := 0; i < fileList.GetItemCount(); i++ {
-		mainText, _ := fileList.GetItemText(i)
-		// Replace color codes with an empty string to remove them
-		plainString := re.ReplaceAllString(mainText, "")
-		fileList.SetItemText(i, plainString, "") // Highlight active file in light green
-	}
-
 	for i := 0; i < fileList.GetItemCount(); i++ {
 		mainText, _ := fileList.GetItemText(i)
+		// Remove the highlight of the previously active file, keeping any other tags
+		if strings.HasPrefix(mainText, activeFileColor) {
+			mainText = strings.TrimSuffix(strings.TrimPrefix(mainText, activeFileColor), "[-]")
+		}
 		if i == activeFileIndex {
-			fileList.SetItemText(i, "[lightgreen]"+mainText+"[-]", "") // Highlight active file in light green
+			mainText = activeFileColor + mainText + "[-]" // Highlight active file in light green
 		}
+		fileList.SetItemText(i, mainText, "")
 	}
 }
 
 // updatePaneFocus updates the border style of the focused pane
 func updatePaneFocus(fileList *tview.List, fileContent *tview.TextView, isFileListFocused bool) {
 	if isFileListFocused {
-		fileList.SetBorder(true).SetBorderColor(tcell.ColorGreen).SetTitle("Files")
+		fileList.SetBorder(true).SetBorderColor(tcell.ColorGreen)
 		fileContent.SetBorder(true).SetBorderColor(tcell.ColorGray)
 	} else {
-		fileList.SetBorder(true).SetBorderColor(tcell.ColorGray).SetTitle("Files")
+		fileList.SetBorder(true).SetBorderColor(tcell.ColorGray)
 		fileContent.SetBorder(true).SetBorderColor(tcell.ColorGreen)
 	}
 }
@@ -515,11 +646,10 @@ func updatePaneFocus(fileList *tview.List, fileContent *tview.TextView, isFileLi
 func (state *appState) cancelSearch() {
 	state.searchMode = false
 	state.searchString = ""
-	state.searchResults = nil
-	state.currentSearchIndex = 0
-	state.fileContent.Highlight("")
+	state.performSearch() // Clears the results and any filter view narrowing
 	state.debugView.SetText("")
-	state.app.SetFocus(state.fileContent)
+	state.statusRow.SwitchToPage("status")
+	state.returnToMain()
 }
 
 func (state *appState) findNextResult() {
@@ -528,7 +658,7 @@ func (state *appState) findNextResult() {
 	}
 	state.currentSearchIndex = (state.currentSearchIndex + 1) % len(state.searchResults)
 	state.highlightCurrentResult()
-	state.debugView.SetText(fmt.Sprintf("Result %d of %d", state.currentSearchIndex+1, len(state.searchResults)))
+	state.showResultCounter()
 }
 
 func (state *appState) findPreviousResult() {
@@ -537,68 +667,207 @@ func (state *appState) findPreviousResult() {
 	}
 	state.currentSearchIndex = (state.currentSearchIndex - 1 + len(state.searchResults)) % len(state.searchResults)
 	state.highlightCurrentResult()
-	state.debugView.SetText(fmt.Sprintf("Result %d of %d", state.currentSearchIndex+1, len(state.searchResults)))
+	state.showResultCounter()
 }
 
+// highlightCurrentResult scrolls to the current result and keeps the other pane at the same node
 func (state *appState) highlightCurrentResult() {
+	for _, pane := range state.panes {
+		if pane.view != nil {
+			pane.view.Highlight()
+		}
+	}
 	if len(state.searchResults) == 0 {
 		return
 	}
 	result := state.searchResults[state.currentSearchIndex]
-	state.fileContent.Highlight(strconv.Itoa(result.line))
-	state.fileContent.ScrollTo(result.pos, result.line)
+	state.panes[result.pane].view.Highlight(searchRegion(state.currentSearchIndex)).ScrollToHighlight()
+	state.syncPanes(result)
+	state.syncMatchList()
 }
 
-func (state *appState) performSearch() {
-	if state.searchString == "" {
+// openFile loads a file from the file list in the background and shows it in
+// the content pane, expanding the directories of the file tree that lead to
+// it. opened, if set, runs once the file is shown.
+func (state *appState) openFile(file string, opened func()) {
+	if state.switchToFile(file) {
+		if opened != nil {
+			opened()
+		}
 		return
 	}
-	content := state.fileContent.GetText(true)
-	lines := strings.Split(content, "\n")
-	state.searchResults = nil
-	for i, line := range lines {
-		index := strings.Index(line, state.searchString)
-		for index != -1 {
-			state.searchResults = append(state.searchResults, struct{ line, pos int }{i, index})
-			index = strings.Index(line[index+len(state.searchString):], state.searchString)
-			if index != -1 {
-				index += len(state.searchString)
+	if state.blockedByEdits() {
+		return
+	}
+	state.stopFollow()
+	checkSnapshot := state.prepareSnapshot(file)
+	state.runLoad(loadMain, filepath.Base(file), func(ctx context.Context, job *loadJob) (func(), error) {
+		value, err := job.readFile(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadFormatting)
+		document := newFormattedDocument(value, nil)
+		candidate := checkSnapshot(value, job.size.Load())
+
+		return func() {
+			state.setContentDocument(file, document)
+			state.fileContent.SetTitle(filepath.Base(file))
+			state.recordSnapshot(file, candidate)
+
+			if state.fileFilter == "" && state.expandToFile(file) {
+				state.refreshFileList()
+			}
+			state.markActiveFile()
+			state.refreshTabs()
+			if opened != nil {
+				opened()
 			}
+		}, nil
+	})
+}
+
+// performSearch finds every match of the search string in the content pane
+// and marks them in place
+func (state *appState) performSearch() error {
+	defer state.renderContent()
+	return state.updateSearchResults()
+}
+
+// reloadFiles repopulates the file list for the current mode
+func (state *appState) reloadFiles() {
+	if state.leftRoot != "" {
+		state.reloadDirectoryPairs()
+		return
+	}
+	state.reloadJSONFiles(state.rootDir)
+}
+
+// reloadJSONFiles walks dir in the background and streams the JSON files it
+// finds into the file list, then watches them for changes and validates
+// them. A reload cancels the walk, watch and validation started before it.
+func (state *appState) reloadJSONFiles(dir string) {
+	if state.cancelWalk != nil {
+		state.cancelWalk()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	state.cancelWalk = cancel
+	state.walkGeneration++
+	generation := state.walkGeneration
+
+	state.allFiles = nil
+	state.walkFailures = nil
+	state.validations = map[string]fileValidation{}
+	state.walking = true
+	state.refreshFileList()
+	state.debugView.SetText("Looking for JSON files in " + tview.Escape(dir) + "…")
+
+	options := state.discovery
+	go func() {
+		walkCtx, walkCancel := context.WithTimeout(ctx, 30*time.Second)
+		defer walkCancel()
+
+		var files, allFound []string
+		dirs := []string{dir}
+		var failures []walkFailure
+		// flush hands what was found so far to the UI, dropping it if a newer walk started
+		flush := func(done bool, err error) {
+			found, failed := files, failures
+			files, failures = nil, nil
+			state.app.QueueUpdateDraw(func() {
+				if generation != state.walkGeneration {
+					return
+				}
+				state.allFiles = append(state.allFiles, found...)
+				state.walkFailures = append(state.walkFailures, failed...)
+				state.walking = !done
+				if !done {
+					state.appendFileRows(found)
+					return
+				}
+				state.refreshFileList()
+				state.finishWalk(err)
+			})
+		}
+
+		lastFlush := time.Now()
+		err := walkDocuments(walkCtx, dir, options, func(path string, isDir bool) {
+			if isDir {
+				dirs = append(dirs, path)
+				return
+			}
+			files = append(files, path)
+			allFound = append(allFound, path)
+			if time.Since(lastFlush) >= walkFlushInterval {
+				flush(false, nil)
+				lastFlush = time.Now()
+			}
+		}, func(failure walkFailure) {
+			errorLogger.Printf("Skipped %s: %v", failure.path, failure.err)
+			failures = append(failures, failure)
+		})
+		flush(true, err)
+		state.watchFiles(ctx, generation, dir, options, dirs)
+		state.runValidation(ctx, generation, allFound)
+	}()
+}
+
+// renderContent redraws the content panes with the current search results marked
+func (state *appState) renderContent() {
+	for i, pane := range state.panes {
+		if pane.view != nil && (i == mainPane || pane.lines != nil) {
+			results, ids := state.paneResults(i)
+			pane.view.SetText(highlightSearchResult(pane.text, results, ids))
 		}
 	}
-	state.currentSearchIndex = 0
-	if len(state.searchResults) > 0 {
-		state.highlightCurrentResult()
-		state.debugView.SetText(fmt.Sprintf("Found %d occurrences. Result 1 of %d. Press 'n' for next, 'N' for previous.", len(state.searchResults), len(state.searchResults)))
+	state.highlightCurrentResult()
+	state.refreshMatchList()
+}
+
+// returnToMain restores the main layout after a modal view and refocuses the active pane
+func (state *appState) returnToMain() {
+	state.app.SetRoot(state.rootLayout, true)
+	if state.isFileListFocused {
+		state.app.SetFocus(state.fileList)
 	} else {
-		state.debugView.SetText("[red]No results found for: " + state.searchString + "[-]")
+		state.app.SetFocus(state.fileContent)
 	}
 }
 
+// setContentDocument shows a formatted document in the main content pane,
+// keeping an active search highlighted in the new content
+func (state *appState) setContentDocument(file string, document formattedDocument) {
+	state.panes[mainPane].document = document
+	state.fileContent.SetTitle(file)
+	state.activeFile = file
+
+	if err := state.performSearch(); err != nil {
+		errorLogger.Printf("Search for %q failed: %v", state.searchString, err)
+	}
+}
+
+// setContentValue formats a parsed document and shows it in the main content pane
+func (state *appState) setContentValue(file string, value interface{}) {
+	state.setContentDocument(file, newFormattedDocument(value, nil))
+}
+
 func (state *appState) setupKeyBindings() {
 	state.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if state.searchMode {
-			switch event.Key() {
-			case tcell.KeyEsc:
-				state.cancelSearch()
-			case tcell.KeyEnter:
-				state.searchMode = false
-				state.performSearch()
-				state.app.SetFocus(state.fileContent)
-			case tcell.KeyRune:
-				state.searchString += string(event.Rune())
-				state.debugView.SetText("Search: " + state.searchString)
-			}
-			return nil
+		// Leave keys to forms, pickers and input fields while they have focus
+		if focus := state.app.GetFocus(); focus != state.fileList && focus != state.fileContent && focus != state.secondFileContent {
+			return event
 		}
 
 		switch event.Key() {
 		case tcell.KeyRight, tcell.KeyLeft:
 			state.isFileListFocused = !state.isFileListFocused
 			updatePaneFocus(state.fileList, state.fileContent, state.isFileListFocused)
+			state.blurComparePane()
 			if state.isFileListFocused {
 				state.app.SetFocus(state.fileList)
-				state.fileList.SetCurrentItem(state.activeFileIndex)
+				if state.activeFileIndex >= 0 {
+					state.fileList.SetCurrentItem(state.activeFileIndex)
+				}
 			} else {
 				state.app.SetFocus(state.fileContent)
 			}
@@ -606,44 +875,128 @@ func (state *appState) setupKeyBindings() {
 			if !state.isFileListFocused {
 				handleScroll(event, state.fileContent, state.secondFileContent, &state.scrollOffset)
 			}
+		case tcell.KeyCtrlP:
+			state.showFileFilter()
+			return nil
 		case tcell.KeyTab:
+			if state.secondFileVisible && state.app.GetFocus() == state.fileContent {
+				state.focusComparePane()
+				return nil
+			}
 			state.isFileListFocused = !state.isFileListFocused
 			updatePaneFocus(state.fileList, state.fileContent, state.isFileListFocused)
+			state.blurComparePane()
 			if state.isFileListFocused {
 				state.app.SetFocus(state.fileList)
-				state.fileList.SetCurrentItem(state.activeFileIndex)
+				if state.activeFileIndex >= 0 {
+					state.fileList.SetCurrentItem(state.activeFileIndex)
+				}
 			} else {
 				state.app.SetFocus(state.fileContent)
 			}
 		case tcell.KeyEnter:
-			if state.isFileListFocused {
+			if state.isFileListFocused && state.fileList.GetCurrentItem() < len(state.actionFuncs) {
+				if index := state.fileList.GetCurrentItem(); index < len(state.files) && state.files[index] == "" {
+					state.actionFuncs[index]() // Expand or collapse the directory
+					return nil
+				}
+				if state.blockedByEdits() {
+					return nil
+				}
 				state.activeFileIndex = state.fileList.GetCurrentItem()
 				state.actionFuncs[state.activeFileIndex]()
 				updateActiveFileHighlight(state.fileList, state.activeFileIndex)
 				state.isFileListFocused = false
 				updatePaneFocus(state.fileList, state.fileContent, state.isFileListFocused)
 				state.app.SetFocus(state.fileContent)
+				return nil // The list would otherwise run the action a second time
 			}
 		case tcell.KeyRune:
 			switch event.Rune() {
 			case 'q', 'Q':
+				if unsaved := state.unsavedTabs(); unsaved > 0 && !state.quitWarned {
+					state.quitWarned = true
+					state.debugView.SetText(fmt.Sprintf("[yellow]Unsaved edits in %d of %d tabs: W writes and U discards those of the shown tab. Press q again to quit anyway.[-]", unsaved, len(state.tabs)))
+					break
+				}
 				state.app.Stop()
 			case 'r', 'R':
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
-				reloadJSONFiles(ctx, state.fileList, state.fileContent, state.debugView, ".", &state.actionFuncs, &state.activeFileIndex)
+				state.reloadFiles()
 			case 'c', 'C':
-				toggleCompareView(state.app, state.fileContent, &state.secondFileContent, &state.secondFileVisible, state.mainFlex, state.fileList, state.actionFuncs, state.debugView)
+				if state.leftRoot != "" && !state.secondFileVisible {
+					state.debugView.SetText("Press Enter on a pair to compare it.")
+					break
+				}
+				toggleCompareView(state.app, state.fileContent, &state.secondFileContent, &state.secondFileVisible, state.mainFlex, state.fileList, state.files, &state.compareFile)
+				state.loadComparePane()
+			case 'x', 'X':
+				state.showExportForm()
+			case 'g':
+				state.compareWithRevision("HEAD")
+				return nil // Don't scroll the content to the top
+			case 'G':
+				state.showRevisionPicker()
+				return nil // Don't scroll the content to the bottom
+			case ' ':
+				if state.isFileListFocused {
+					state.toggleFileMark()
+					return nil
+				}
+			case 'm', 'M':
+				state.showDriftMatrix()
 			case 'o', 'O':
 				state.toggleLayout()
 			case 'f', 'F', '?', 'h', 'H':
 				state.showHelpModal()
 			case '/':
 				state.startSearch()
+				return nil // Don't type the slash into the search prompt
 			case 'n':
 				state.findNextResult()
 			case 'N':
 				state.findPreviousResult()
+			case 'S':
+				state.showFileSearch()
+				return nil
+			case 'p', 'P':
+				state.showFileFilter()
+				return nil
+			case 'D':
+				state.showRootForm()
+				return nil
+			case 'l':
+				state.toggleMatchList()
+				return nil
+			case 'v', 'V':
+				state.toggleFilterView()
+			case 'e':
+				state.showReplaceForm()
+				return nil
+			case 'E':
+				state.showDiagnostics()
+				return nil
+			case 's':
+				state.cycleSortOrder()
+			case 'i':
+				state.showColumnsForm()
+				return nil
+			case 't':
+				state.toggleFollow()
+			case 'T':
+				state.toggleTimeline()
+				return nil
+			case '[':
+				state.switchTab(-1)
+			case ']':
+				state.switchTab(1)
+			case 'w':
+				state.closeTab()
+			case 'W':
+				state.saveEdits()
+			case 'U':
+				state.discardEdits()
+			case 'a':
+				state.openInNewTab()
 			}
 		}
 		return event
@@ -656,29 +1009,93 @@ func (state *appState) showHelpModal() {
 - q/Q: Quit
 - Arrows: Navigate between files and content
 - Enter: Open selected file
-- r/R: Reload files
+- r/R: Reload files (changes on disk are picked up automatically)
 - c/C: Compare files
+- x/X: Export comparison as patch or diff
+- g: Compare open file with git HEAD
+- G: Compare open file with a git revision
+- Space: Mark file for the drift matrix
+- m/M: Drift matrix of marked files
 - o/O: Toggle layout
-- Tab: Switch focus
-- /: Search
+- Tab: Switch focus, including the compare pane
+- /: Search the focused pane as you type (Alt-c: case, Alt-r: regex,
+  Alt-b: both panes, Up/Down: history)
+  Scopes: key:id, value:text, num:>100, type:null, type:emptyarray, path:**.image
 - n: Next search result
 - N: Previous search result
+- S: Search in all files
+- p/Ctrl-P: Find a file by fuzzy matching its path
+- Enter/Space on a directory: Expand or collapse it
+- D: Change the root directory
+- l: Show or hide the list of matches
+- v: Show only the nodes matching the search
+- e: Find and replace in the open file, writing at once (w) or
+  keeping the edits in the tab unsaved (u)
+- W / U: Write / discard the unsaved edits of the tab (marked ● in the tab bar)
+- E: Skipped paths and files that failed validation
+- s: Sort files by name, size, modification time or status
+- i: Choose the columns of the file list
+- t: Follow the open JSON Lines file as it grows
+- T: Timeline of the open file's snapshots, diffing any two of them
+- a: Open selected file in a new tab (Enter replaces the current tab)
+- [ / ]: Previous / next tab
+- w: Close tab
 - Esc: Cancel search`
 
 	modal := tview.NewModal().
 		SetText(helpText).
 		AddButtons([]string{"Close"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			state.app.SetRoot(state.rootLayout, true).SetFocus(state.fileList)
+			state.returnToMain()
 		})
 	state.app.SetRoot(modal, true).SetFocus(modal)
 }
 
+// showResultCounter reports the position of the current search result in the status row
+func (state *appState) showResultCounter() {
+	result := state.searchResults[state.currentSearchIndex]
+	location := result.path.String()
+	if len(state.searchedPanes()) > 1 {
+		location += " in the " + paneNames[result.pane] + " pane"
+	}
+	state.debugView.SetText(fmt.Sprintf("Result %d of %d for %s at %s (n: next, N: previous)",
+		state.currentSearchIndex+1, len(state.searchResults), tview.Escape(state.searchString), tview.Escape(location)))
+}
+
 func (state *appState) startSearch() {
+	state.searchPane = mainPane
+	if state.secondFileVisible && state.app.GetFocus() == state.secondFileContent {
+		state.searchPane = comparePane
+	}
 	state.searchMode = true
 	state.searchString = ""
-	state.debugView.SetText("Search: ")
-	state.app.SetFocus(state.debugView)
+	state.searchDraft = ""
+	state.historyIndex = len(state.searchHistory)
+	state.searchInput.SetText("")
+	state.updateSearchPrompt()
+	state.statusRow.SwitchToPage("search")
+	state.app.SetFocus(state.searchInput)
+}
+
+// toggleFileMark marks or unmarks the file under the cursor in the file list
+func (state *appState) toggleFileMark() {
+	index := state.fileList.GetCurrentItem()
+	if index < 0 || index >= len(state.files) {
+		return
+	}
+	file := state.files[index]
+	if file == "" {
+		state.toggleDirectory(state.fileRows[index].path)
+		return
+	}
+	if state.markedFiles[file] {
+		delete(state.markedFiles, file)
+	} else {
+		state.markedFiles[file] = true
+	}
+	state.fileList.SetItemText(index, state.fileItemText(state.fileRows[index]), "")
+	updateActiveFileHighlight(state.fileList, state.activeFileIndex)
+	state.debugView.SetText(fmt.Sprintf("%d files marked. Press m for the drift matrix.", len(state.markedFiles)))
 }
 
 func (state *appState) toggleLayout() {
@@ -686,14 +1103,14 @@ func (state *appState) toggleLayout() {
 		if state.layoutHorizontal {
 			state.mainFlex.Clear()
 			state.mainFlex.SetDirection(tview.FlexRow).
-				AddItem(state.fileList, 0, 1, true).
+				AddItem(state.filePanel, 0, 1, true).
 				AddItem(state.fileContent, 0, 1, false).
 				AddItem(state.secondFileContent, 0, 1, false)
 			state.layoutHorizontal = false
 		} else {
 			state.mainFlex.Clear()
 			state.mainFlex.SetDirection(tview.FlexColumn).
-				AddItem(state.fileList, 0, 1, true).
+				AddItem(state.filePanel, 0, 1, true).
 				AddItem(state.fileContent, 0, 2, false).
 				AddItem(state.secondFileContent, 0, 2, false)
 			state.layoutHorizontal = true
@@ -701,3 +1118,80 @@ func (state *appState) toggleLayout() {
 		state.app.ForceDraw()
 	}
 }
+
+// updateSearchResults searches the documents of the searched panes. A
+// "scope:" prefix restricts the search to keys, string values, numbers, types
+// or paths (see parseSearchQuery). In the filter view the panes are narrowed
+// to the matching nodes and the results refer to the narrowed content. When
+// both panes are searched the results are ordered by path, so that stepping
+// through them alternates between the two sides of a node.
+func (state *appState) updateSearchResults() error {
+	state.searchResults = nil
+	state.currentSearchIndex = 0
+	for i := range state.panes {
+		pane := &state.panes[i]
+		pane.text, pane.lines = pane.document.colored, pane.document.lines
+	}
+	if state.searchString == "" {
+		return nil
+	}
+
+	query, err := parseSearchQuery(state.searchString)
+	if err != nil {
+		return err
+	}
+	for _, index := range state.searchedPanes() {
+		pane := &state.panes[index]
+		if pane.document.lines == nil {
+			continue
+		}
+		results, err := searchDocument(pane.document.text, pane.document.lines, query, state.searchOptions)
+		if err != nil {
+			return err
+		}
+		if state.filterView {
+			filtered := newFormattedDocument(pane.document.value, newNodeFilter(results))
+			pane.text, pane.lines = filtered.colored, filtered.lines
+			if results, err = searchDocument(filtered.text, filtered.lines, query, state.searchOptions); err != nil {
+				return err
+			}
+		}
+		for _, result := range results {
+			result.pane = index
+			state.searchResults = append(state.searchResults, result)
+		}
+	}
+
+	if len(state.searchedPanes()) > 1 {
+		sort.SliceStable(state.searchResults, func(i, j int) bool {
+			a, b := state.searchResults[i], state.searchResults[j]
+			if pathLess(a.path, b.path) || pathLess(b.path, a.path) {
+				return pathLess(a.path, b.path)
+			}
+			return a.pane < b.pane
+		})
+	}
+	return nil
+}
+
+// updateSearchPrompt shows the active search mode and the number of matches
+// next to the search input
+func (state *appState) updateSearchPrompt() {
+	state.searchInput.SetLabel(fmt.Sprintf("Search (%s): ", state.searchTarget()))
+	if state.searchString == "" {
+		state.searchInfo.SetText("Alt-c case, Alt-r regex, Alt-b both panes, ↑/↓ history, key: value: num: type: path:")
+		return
+	}
+	state.searchInfo.SetText(fmt.Sprintf("%d matches", len(state.searchResults)))
+}
+
+func (options searchOptions) String() string {
+	mode := "case-insensitive"
+	if options.caseSensitive {
+		mode = "case-sensitive"
+	}
+	if options.useRegex {
+		return mode + " regex"
+	}
+	return mode + " literal"
+}
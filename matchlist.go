@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Height of the match list panel, including its border and filter field
+const matchPanelHeight = 12
+
+// focusContent moves the focus back to the content pane
+func (state *appState) focusContent() {
+	state.isFileListFocused = false
+	updatePaneFocus(state.fileList, state.fileContent, state.isFileListFocused)
+	state.blurComparePane()
+	state.app.SetFocus(state.fileContent)
+}
+
+// jumpToMatch selects a search result and shows it in the content pane
+func (state *appState) jumpToMatch(index int) {
+	if index < 0 || index >= len(state.searchResults) {
+		return
+	}
+	state.currentSearchIndex = index
+	state.highlightCurrentResult()
+	state.showResultCounter()
+	if state.searchResults[index].pane == comparePane {
+		state.focusComparePane()
+	} else {
+		state.focusContent()
+	}
+}
+
+// refreshMatchList lists the search results that pass the filter
+func (state *appState) refreshMatchList() {
+	if !state.matchListVisible {
+		return
+	}
+	state.matchList.Clear()
+	state.matchIndices = nil
+
+	filter := strings.ToLower(state.matchFilter.GetText())
+	var lines [len(state.panes)][]string
+	for i, pane := range state.panes {
+		lines[i] = strings.Split(contentTagRegex.ReplaceAllString(pane.text, ""), "\n")
+	}
+	bothPanes := len(state.searchedPanes()) > 1
+	for i, result := range state.searchResults {
+		snippet := ""
+		if paneLines := lines[result.pane]; result.line < len(paneLines) {
+			snippet = strings.TrimSpace(paneLines[result.line])
+		}
+		text := fmt.Sprintf("%5d:%-3d  %s  %s", result.line+1, result.start+1, result.path.String(), snippet)
+		if bothPanes {
+			text = fmt.Sprintf("%-5s %s", paneNames[result.pane], text)
+		}
+		if filter != "" && !strings.Contains(strings.ToLower(text), filter) {
+			continue
+		}
+		index := i
+		state.matchList.AddItem(tview.Escape(text), "", 0, func() {
+			state.jumpToMatch(index)
+		})
+		state.matchIndices = append(state.matchIndices, i)
+	}
+
+	title := fmt.Sprintf("Matches: %d", len(state.searchResults))
+	if filter != "" {
+		title = fmt.Sprintf("Matches: %d of %d", len(state.matchIndices), len(state.searchResults))
+	}
+	state.matchPanel.SetTitle(title + " (Enter: jump, Tab: filter, Esc: back, l: hide)")
+	state.syncMatchList()
+}
+
+// setupMatchList builds the match list panel shown below the content by 'l'
+func (state *appState) setupMatchList() {
+	state.matchFilter = tview.NewInputField().SetLabel("Filter: ").SetFieldBackgroundColor(tcell.ColorDefault)
+	state.matchList = tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	state.matchPanel = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(state.matchFilter, 1, 0, false).
+		AddItem(state.matchList, 0, 1, true)
+	state.matchPanel.SetBorder(true)
+
+	state.matchFilter.SetChangedFunc(func(text string) {
+		state.refreshMatchList()
+	})
+	state.matchFilter.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter, tcell.KeyTab:
+			state.app.SetFocus(state.matchList)
+		case tcell.KeyEscape:
+			state.focusContent()
+		}
+	})
+	state.matchList.SetDoneFunc(state.focusContent)
+	state.matchList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyTab:
+			state.app.SetFocus(state.matchFilter)
+			return nil
+		case event.Key() == tcell.KeyRune && event.Rune() == 'l':
+			state.toggleMatchList()
+			return nil
+		}
+		return event
+	})
+}
+
+// syncMatchList moves the list cursor to the current search result
+func (state *appState) syncMatchList() {
+	if !state.matchListVisible {
+		return
+	}
+	for row, index := range state.matchIndices {
+		if index == state.currentSearchIndex {
+			state.matchList.SetCurrentItem(row)
+			return
+		}
+	}
+}
+
+// toggleMatchList shows or hides the match list panel below the content
+func (state *appState) toggleMatchList() {
+	state.matchListVisible = !state.matchListVisible
+
+	state.arrangeRows()
+
+	if !state.matchListVisible {
+		state.focusContent()
+		return
+	}
+	state.refreshMatchList()
+	state.app.SetFocus(state.matchList)
+	if len(state.searchResults) == 0 {
+		state.debugView.SetText("No search results. Press / to search.")
+	}
+}
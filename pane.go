@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Indices into appState.panes
+const (
+	mainPane = iota
+	comparePane
+)
+
+// Labels of the panes in search messages
+var paneNames = [...]string{mainPane: "left", comparePane: "right"}
+
+// contentPane is a text view showing a document that can be searched
+type contentPane struct {
+	view     *tview.TextView
+	document formattedDocument
+	text     string // coloured text as displayed, narrowed in the filter view
+	lines    []contentLine
+}
+
+// resultsByLine sorts the results of one pane with their region IDs
+type resultsByLine struct {
+	results []searchResult
+	ids     []int
+}
+
+// searchRegion is the region ID that marks a search result in a pane
+func searchRegion(id int) string {
+	return "m" + strconv.Itoa(id)
+}
+
+func (r resultsByLine) Len() int { return len(r.results) }
+
+func (r resultsByLine) Less(i, j int) bool {
+	if r.results[i].line != r.results[j].line {
+		return r.results[i].line < r.results[j].line
+	}
+	return r.results[i].start < r.results[j].start
+}
+
+func (r resultsByLine) Swap(i, j int) {
+	r.results[i], r.results[j] = r.results[j], r.results[i]
+	r.ids[i], r.ids[j] = r.ids[j], r.ids[i]
+}
+
+// blurComparePane shows that the compare pane has lost the focus
+func (state *appState) blurComparePane() {
+	if state.secondFileContent != nil {
+		state.secondFileContent.SetBorderColor(tcell.ColorGray)
+	}
+}
+
+// focusComparePane moves the focus to the compare pane
+func (state *appState) focusComparePane() {
+	state.isFileListFocused = false
+	state.fileList.SetBorderColor(tcell.ColorGray)
+	state.fileContent.SetBorderColor(tcell.ColorGray)
+	state.secondFileContent.SetBorderColor(tcell.ColorGreen)
+	state.app.SetFocus(state.secondFileContent)
+}
+
+// loadComparePane loads the compare file in the background after the compare
+// view was toggled or the file changed, makes it searchable and diffs the
+// open document against it
+func (state *appState) loadComparePane() {
+	state.panes[comparePane].view = state.secondFileContent
+	if !state.secondFileVisible || state.compareFile == "" {
+		state.cancelLoad(loadCompare)
+		state.panes[comparePane].document = formattedDocument{}
+		state.comparison = nil
+		if err := state.performSearch(); err != nil {
+			errorLogger.Printf("Search for %q failed: %v", state.searchString, err)
+		}
+		return
+	}
+
+	file := state.compareFile
+	leftFile, left := state.activeFile, state.panes[mainPane].document.value
+	options := state.diffOptions
+	state.runLoad(loadCompare, filepath.Base(file), func(ctx context.Context, job *loadJob) (func(), error) {
+		right, err := job.readFile(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadFormatting)
+		document := newFormattedDocument(right, nil)
+		var cmp *comparison
+		if leftFile != "" {
+			job.phase.Store(loadComparing)
+			cmp = &comparison{
+				leftPath:  leftFile,
+				rightPath: file,
+				left:      left,
+				right:     right,
+				options:   options,
+				ops:       diffValues(nil, left, right, options),
+			}
+		}
+
+		return func() {
+			// Keep the place in the pane when the same file is loaded again
+			row, column := state.secondFileContent.GetScrollOffset()
+			state.panes[comparePane].document = document
+			state.comparison = cmp
+			if err := state.performSearch(); err != nil {
+				errorLogger.Printf("Search for %q failed: %v", state.searchString, err)
+			}
+			state.secondFileContent.ScrollTo(row, column)
+
+			switch {
+			case cmp == nil:
+				state.debugView.SetText("Open a file with Enter to compare it against the selected file.")
+			case len(cmp.ops) == 0:
+				state.debugView.SetText("Documents are identical.")
+			default:
+				state.debugView.SetText(fmt.Sprintf("%d differences. Press x to export.", len(cmp.ops)))
+			}
+		}, nil
+	})
+}
+
+// paneResults returns the search results in a pane in line order, together
+// with their indices in state.searchResults
+func (state *appState) paneResults(pane int) ([]searchResult, []int) {
+	var results []searchResult
+	var ids []int
+	for i, result := range state.searchResults {
+		if result.pane == pane {
+			results = append(results, result)
+			ids = append(ids, i)
+		}
+	}
+	sort.Sort(resultsByLine{results, ids})
+	return results, ids
+}
+
+// searchedPanes lists the panes the current search covers
+func (state *appState) searchedPanes() []int {
+	if !state.secondFileVisible {
+		return []int{mainPane}
+	}
+	if state.searchBothPanes {
+		return []int{mainPane, comparePane}
+	}
+	return []int{state.searchPane}
+}
+
+// searchTarget describes the search options and the panes searched
+func (state *appState) searchTarget() string {
+	switch {
+	case !state.secondFileVisible:
+		return state.searchOptions.String()
+	case state.searchBothPanes:
+		return state.searchOptions.String() + ", both panes"
+	default:
+		return state.searchOptions.String() + ", " + paneNames[state.searchPane] + " pane"
+	}
+}
+
+// syncPanes scrolls the pane without the current result to the same node
+func (state *appState) syncPanes(result searchResult) {
+	if !state.secondFileVisible {
+		return
+	}
+	other := &state.panes[1-result.pane]
+	if other.view == nil || other.lines == nil {
+		return
+	}
+	other.view.ScrollTo(anchorLine(other.lines, result.path), 0)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// pathPattern matches JSON paths. Each segment is a glob for one key or
+// index; the segment "**" matches any number of segments.
+type pathPattern []string
+
+// parsePathPattern accepts either a JSON Pointer ("/metadata/*/time") or the
+// dotted form shown in the UI ("**.image", "spec.containers[*].name").
+func parsePathPattern(pattern string) (pathPattern, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty path pattern")
+	}
+
+	var segments pathPattern
+	if strings.HasPrefix(pattern, "/") {
+		for _, token := range strings.Split(pattern[1:], "/") {
+			segments = append(segments, strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~"))
+		}
+	} else {
+		rest := strings.TrimPrefix(pattern, ".")
+		for rest != "" {
+			switch {
+			case strings.HasPrefix(rest, "[\""):
+				end := strings.Index(rest, "\"]")
+				if end == -1 {
+					return nil, fmt.Errorf("unterminated key in path pattern %q", pattern)
+				}
+				key, err := strconv.Unquote(rest[1 : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid key in path pattern %q: %w", pattern, err)
+				}
+				segments = append(segments, key)
+				rest = rest[end+2:]
+			case strings.HasPrefix(rest, "["):
+				end := strings.Index(rest, "]")
+				if end == -1 {
+					return nil, fmt.Errorf("unterminated index in path pattern %q", pattern)
+				}
+				segments = append(segments, rest[1:end])
+				rest = rest[end+1:]
+			default:
+				end := strings.IndexAny(rest, ".[")
+				if end == -1 {
+					end = len(rest)
+				}
+				if end == 0 {
+					return nil, fmt.Errorf("empty segment in path pattern %q", pattern)
+				}
+				segments = append(segments, rest[:end])
+				rest = rest[end:]
+			}
+			rest = strings.TrimPrefix(rest, ".")
+		}
+	}
+
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q in path pattern %q", segment, pattern)
+		}
+	}
+	return segments, nil
+}
+
+func matchSegments(pattern pathPattern, tokens []string) bool {
+	if len(pattern) == 0 {
+		return len(tokens) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(tokens); i++ {
+			if matchSegments(pattern[1:], tokens[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(tokens) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], tokens[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], tokens[1:])
+}
+
+func (p pathPattern) match(jp jsonPath) bool {
+	tokens := make([]string, len(jp))
+	for i, token := range jp {
+		switch t := token.(type) {
+		case int:
+			tokens[i] = strconv.Itoa(t)
+		case string:
+			tokens[i] = t
+		}
+	}
+	return matchSegments(p, tokens)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    jsonPath
+		want    bool
+	}{
+		{"**.image", jsonPath{"spec", "containers", 0, "image"}, true},
+		{"**.image", jsonPath{"image"}, true},
+		{"**.image", jsonPath{"spec", "imageName"}, false},
+		{"spec.containers[*].name", jsonPath{"spec", "containers", 3, "name"}, true},
+		{"spec.containers[*].name", jsonPath{"spec", "containers", "name"}, false},
+		{"/metadata/*/time", jsonPath{"metadata", "created", "time"}, true},
+		{"/metadata/*/time", jsonPath{"metadata", "time"}, false},
+		{"/a~1b/c~0d", jsonPath{"a/b", "c~d"}, true},
+		{`["a.b"].c`, jsonPath{"a.b", "c"}, true},
+		{"items[1?]", jsonPath{"items", 12}, true},
+		{"items[1?]", jsonPath{"items", 2}, false},
+		{"a.**", jsonPath{"a"}, true},
+		{"a.**.z", jsonPath{"a", "b", "c", "z"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			pattern, err := parsePathPattern(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pattern.match(test.path); got != test.want {
+				t.Errorf("match(%s) = %v, want %v", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePathPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"", `["a`, "a[1", "a..b", "a[[]"} {
+		if _, err := parsePathPattern(pattern); err == nil {
+			t.Errorf("parsePathPattern(%q) succeeded, want an error", pattern)
+		}
+	}
+}
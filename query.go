@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Search scopes recognised as a "scope:" prefix of the search string
+const (
+	scopeText  = ""
+	scopeKey   = "key"
+	scopeValue = "value"
+	scopeNum   = "num"
+	scopeType  = "type"
+	scopePath  = "path"
+)
+
+// Type names accepted by type: searches
+var searchTypes = []string{"null", "bool", "number", "string", "array", "object", "empty", "emptyarray", "emptyobject"}
+
+// contentLine describes one line of a formatted document: the path of the
+// value that starts on it and where its key and value sit within the line.
+// Lines that only close an object or array have closing set, and lines
+// standing for hidden siblings in a filtered view have elided set.
+type contentLine struct {
+	path       jsonPath
+	value      interface{}
+	closing    bool
+	elided     bool
+	keyStart   int // -1 when the value has no key
+	keyEnd     int
+	valueStart int
+	valueEnd   int
+}
+
+// searchQuery is a parsed search string
+type searchQuery struct {
+	scope    string
+	text     string
+	operator string
+	number   float64
+	pattern  pathPattern
+}
+
+// compareNumber applies a num: comparison
+func compareNumber(value float64, operator string, number float64) bool {
+	switch operator {
+	case ">":
+		return value > number
+	case ">=":
+		return value >= number
+	case "<":
+		return value < number
+	case "<=":
+		return value <= number
+	case "!=":
+		return value != number
+	default:
+		return value == number
+	}
+}
+
+// formatJSON pretty prints value exactly like json.MarshalIndent with a two
+// space indent and records what each output line holds.
+func formatJSON(value interface{}) (string, []contentLine) {
+	return formatNodes(value, nil)
+}
+
+// formatNodes pretty prints value like formatJSON. With a filter only the
+// nodes it selects are written and each run of hidden siblings becomes a
+// single "…" line.
+func formatNodes(value interface{}, filter *nodeFilter) (string, []contentLine) {
+	var out strings.Builder
+	var lines []contentLine
+
+	var write func(path jsonPath, key string, hasKey bool, value interface{}, indent string, last, full bool)
+	write = func(path jsonPath, key string, hasKey bool, value interface{}, indent string, last, full bool) {
+		line := contentLine{path: path, value: value, keyStart: -1, keyEnd: -1}
+		text := indent
+		if hasKey {
+			encodedKey, _ := json.Marshal(key)
+			line.keyStart = len(text) + 1
+			line.keyEnd = len(text) + len(encodedKey) - 1
+			text += string(encodedKey) + ": "
+		}
+		comma := ","
+		if last {
+			comma = ""
+		}
+
+		var open, close string
+		var children int
+		switch v := value.(type) {
+		case map[string]interface{}:
+			open, close, children = "{", "}", len(v)
+		case []interface{}:
+			open, close, children = "[", "]", len(v)
+		default:
+			encoded, _ := json.Marshal(v)
+			line.valueStart = len(text)
+			line.valueEnd = len(text) + len(encoded)
+			out.WriteString(text + string(encoded) + comma + "\n")
+			lines = append(lines, line)
+			return
+		}
+
+		line.valueStart = len(text)
+		if children == 0 {
+			line.valueEnd = len(text) + 2
+			out.WriteString(text + open + close + comma + "\n")
+			lines = append(lines, line)
+			return
+		}
+		line.valueEnd = len(text) + 1
+		out.WriteString(text + open + "\n")
+		lines = append(lines, line)
+
+		// Collect the children in output order, then decide which are shown
+		type child struct {
+			path   jsonPath
+			key    string
+			hasKey bool
+			value  interface{}
+		}
+		var all []child
+		switch v := value.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				all = append(all, child{path.child(k), k, true, v[k]})
+			}
+		case []interface{}:
+			for i, element := range v {
+				all = append(all, child{path.child(i), "", false, element})
+			}
+		}
+
+		type item struct {
+			child  child
+			full   bool
+			elided bool
+		}
+		var items []item
+		for _, c := range all {
+			switch {
+			case full || filter == nil:
+				items = append(items, item{child: c, full: full})
+			case filter.matched[c.path.pointer()]:
+				items = append(items, item{child: c, full: true})
+			case filter.ancestors[c.path.pointer()]:
+				items = append(items, item{child: c})
+			case len(items) == 0 || !items[len(items)-1].elided:
+				items = append(items, item{elided: true})
+			}
+		}
+		childIndent := indent + "  "
+		for i, it := range items {
+			if it.elided {
+				elidedComma := ","
+				if i == len(items)-1 {
+					elidedComma = ""
+				}
+				out.WriteString(childIndent + "…" + elidedComma + "\n")
+				lines = append(lines, contentLine{path: path, value: value, elided: true, keyStart: -1, keyEnd: -1,
+					valueStart: len(childIndent), valueEnd: len(childIndent) + len("…")})
+				continue
+			}
+			write(it.child.path, it.child.key, it.child.hasKey, it.child.value, childIndent, i == len(items)-1, it.full)
+		}
+
+		out.WriteString(indent + close + comma + "\n")
+		lines = append(lines, contentLine{path: path, value: value, closing: true, keyStart: -1, keyEnd: -1,
+			valueStart: len(indent), valueEnd: len(indent) + 1})
+	}
+	write(nil, "", false, value, "", true, filter == nil || filter.matched[jsonPath(nil).pointer()])
+
+	return strings.TrimSuffix(out.String(), "\n"), lines
+}
+
+// matchesType reports whether value is of the named search type
+func matchesType(value interface{}, typeName string) bool {
+	switch v := value.(type) {
+	case nil:
+		return typeName == "null"
+	case bool:
+		return typeName == "bool"
+	case float64:
+		return typeName == "number"
+	case string:
+		return typeName == "string"
+	case []interface{}:
+		return typeName == "array" || (len(v) == 0 && (typeName == "emptyarray" || typeName == "empty"))
+	case map[string]interface{}:
+		return typeName == "object" || (len(v) == 0 && (typeName == "emptyobject" || typeName == "empty"))
+	}
+	return false
+}
+
+// parseSearchQuery splits a search string into its scope and argument.
+// Strings without a known scope prefix are plain text searches.
+func parseSearchQuery(search string) (searchQuery, error) {
+	scope, argument, found := strings.Cut(search, ":")
+	if !found {
+		return searchQuery{scope: scopeText, text: search}, nil
+	}
+	query := searchQuery{scope: strings.ToLower(strings.TrimSpace(scope)), text: argument}
+
+	switch query.scope {
+	case scopeKey, scopeValue:
+		if query.text == "" {
+			return query, fmt.Errorf("%s: needs a search string", query.scope)
+		}
+	case scopeNum:
+		argument = strings.TrimSpace(argument)
+		for _, operator := range []string{">=", "<=", "!=", ">", "<", "="} {
+			if strings.HasPrefix(argument, operator) {
+				query.operator = operator
+				argument = strings.TrimSpace(strings.TrimPrefix(argument, operator))
+				break
+			}
+		}
+		number, err := strconv.ParseFloat(argument, 64)
+		if err != nil {
+			return query, fmt.Errorf("num: expects a comparison such as > 100")
+		}
+		query.number = number
+	case scopeType:
+		query.text = strings.ToLower(strings.TrimSpace(argument))
+		for _, name := range searchTypes {
+			if name == query.text {
+				return query, nil
+			}
+		}
+		return query, fmt.Errorf("type: expects one of %s", strings.Join(searchTypes, ", "))
+	case scopePath:
+		pattern, err := parsePathPattern(strings.TrimSpace(argument))
+		if err != nil {
+			return query, err
+		}
+		query.pattern = pattern
+	default:
+		return searchQuery{scope: scopeText, text: search}, nil
+	}
+	return query, nil
+}
+
+// searchDocument finds every match of a query in a formatted document; text
+// is the plain output of formatJSON and lines describes its lines.
+func searchDocument(text string, lines []contentLine, query searchQuery, options searchOptions) ([]searchResult, error) {
+	if query.scope != scopeText {
+		return searchLines(text, lines, query, options)
+	}
+
+	re, err := compileSearch(query.text, options)
+	if err != nil {
+		return nil, err
+	}
+	var results []searchResult
+	for i, line := range strings.Split(text, "\n") {
+		matches := findMatches(line, re)
+		var path jsonPath
+		if i < len(lines) {
+			path = lines[i].path
+		}
+		for _, match := range matches {
+			results = append(results, searchResult{line: i, start: match[0], end: match[1], path: path})
+		}
+	}
+	return results, nil
+}
+
+// searchLines runs a scoped query over the lines of a formatted document.
+// Results carry the JSON path of the key or value they matched.
+func searchLines(text string, lines []contentLine, query searchQuery, options searchOptions) ([]searchResult, error) {
+	var re *regexp.Regexp
+	if query.scope == scopeKey || query.scope == scopeValue {
+		var err error
+		if re, err = compileSearch(query.text, options); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []searchResult
+	textLines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line.closing || line.elided || i >= len(textLines) {
+			continue
+		}
+		whole := searchResult{line: i, start: line.valueStart, end: line.valueEnd, path: line.path}
+
+		switch query.scope {
+		case scopeKey, scopeValue:
+			start, end := line.keyStart, line.keyEnd
+			if query.scope == scopeValue {
+				if _, ok := line.value.(string); !ok {
+					continue
+				}
+				// Search inside the quotes only
+				start, end = line.valueStart+1, line.valueEnd-1
+			}
+			if start < 0 {
+				continue
+			}
+			for _, match := range findMatches(textLines[i][start:end], re) {
+				results = append(results, searchResult{line: i, start: start + match[0], end: start + match[1], path: line.path})
+			}
+		case scopeNum:
+			if number, ok := line.value.(float64); ok && compareNumber(number, query.operator, query.number) {
+				results = append(results, whole)
+			}
+		case scopeType:
+			if matchesType(line.value, query.text) {
+				results = append(results, whole)
+			}
+		case scopePath:
+			if query.pattern.match(line.path) {
+				if line.keyStart >= 0 {
+					whole.start, whole.end = line.keyStart, line.keyEnd
+				}
+				results = append(results, whole)
+			}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSearchDocument(t *testing.T) {
+	document := decode(t, `{"name":"Alpha","items":[{"name":"beta","size":12},{"label":"alpha","size":3}]}`)
+	text, lines := formatJSON(document)
+	tests := []struct {
+		search  string
+		options searchOptions
+		want    []string // paths of the results, in document order (keys are sorted)
+	}{
+		{"alpha", searchOptions{caseSensitive: true}, []string{".items[1].label"}},
+		{"alpha", searchOptions{}, []string{".items[1].label", ".name"}},
+		{"a.p", searchOptions{useRegex: true}, []string{".items[1].label", ".name"}},
+		{"a.p", searchOptions{}, nil},
+		{"key:name", searchOptions{caseSensitive: true}, []string{".items[0].name", ".name"}},
+		{"value:eta", searchOptions{caseSensitive: true}, []string{".items[0].name"}},
+		{"num:>5", searchOptions{}, []string{".items[0].size"}},
+		{"path:**.size", searchOptions{}, []string{".items[0].size", ".items[1].size"}},
+	}
+	for _, test := range tests {
+		t.Run(test.search, func(t *testing.T) {
+			query, err := parseSearchQuery(test.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := searchDocument(text, lines, query, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.path.String())
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchDocumentInvalidRegex(t *testing.T) {
+	text, lines := formatJSON(decode(t, `{"a":1}`))
+	query, err := parseSearchQuery("(")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := searchDocument(text, lines, query, searchOptions{useRegex: true}); err == nil {
+		t.Error("searching for an invalid regex succeeded, want an error")
+	}
+}
+
+func BenchmarkSearchDocument(b *testing.B) {
+	items := make([]interface{}, 20000)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": float64(i), "name": "item", "tags": []interface{}{"a", "b"}}
+	}
+	text, lines := formatJSON(map[string]interface{}{"items": items})
+	query, err := parseSearchQuery("tem")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := searchDocument(text, lines, query, searchOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,732 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// replacement is one proposed change of a string value, or of a key when key is set
+type replacement struct {
+	path     jsonPath
+	key      bool
+	oldText  string
+	newText  string
+	accepted bool
+}
+
+// tokenSpan is where a string token sits in a document, in bytes
+type tokenSpan struct {
+	start, end int
+}
+
+// spanKey identifies a string token: the value at a path, or the key of the
+// member at a path
+type spanKey struct {
+	pointer string
+	key     bool
+}
+
+// spanScanner finds the string values and keys of a JSON document in its
+// text, so they can be replaced without re-encoding the rest
+type spanScanner struct {
+	data  []byte
+	pos   int
+	spans map[spanKey]tokenSpan
+}
+
+// applyReplacements returns a copy of value with the accepted replacements
+// made. Keys are renamed deepest first so that the paths of the remaining
+// replacements stay valid.
+func applyReplacements(value interface{}, replacements []replacement) (interface{}, error) {
+	result := cloneValue(value)
+
+	var renames []replacement
+	for _, r := range replacements {
+		if !r.accepted {
+			continue
+		}
+		if r.key {
+			renames = append(renames, r)
+			continue
+		}
+		if len(r.path) == 0 {
+			result = r.newText
+			continue
+		}
+		parent := lookupValue(result, r.path[:len(r.path)-1])
+		switch container := parent.(type) {
+		case map[string]interface{}:
+			container[r.path[len(r.path)-1].(string)] = r.newText
+		case []interface{}:
+			container[r.path[len(r.path)-1].(int)] = r.newText
+		}
+	}
+
+	sort.SliceStable(renames, func(i, j int) bool { return len(renames[i].path) > len(renames[j].path) })
+	for _, r := range renames {
+		container, ok := lookupValue(result, r.path[:len(r.path)-1]).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, exists := container[r.newText]; exists {
+			return nil, fmt.Errorf("renaming %s to %q would overwrite an existing key", r.path, r.newText)
+		}
+		container[r.newText] = container[r.oldText]
+		delete(container, r.oldText)
+	}
+	return result, nil
+}
+
+// cloneValue deep-copies a decoded JSON value
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			clone[key] = cloneValue(child)
+		}
+		return clone
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, child := range v {
+			clone[i] = cloneValue(child)
+		}
+		return clone
+	default:
+		return v
+	}
+}
+
+// colorizeUnifiedDiff colours the added, removed and hunk header lines of a unified diff
+func colorizeUnifiedDiff(diff string) string {
+	lines := strings.Split(tview.Escape(diff), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = "[yellow]" + line + "[-]"
+		case strings.HasPrefix(line, "+"):
+			lines[i] = "[green]" + line + "[-]"
+		case strings.HasPrefix(line, "-"):
+			lines[i] = "[red]" + line + "[-]"
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = "[blue]" + line + "[-]"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// encodeJSONString encodes text as a JSON string without escaping HTML characters
+func encodeJSONString(text string) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(text); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+// findReplacements lists every string value, and every key when keys is
+// set, that re matches, with the text it would be replaced by. In regex mode
+// template may refer to capture groups as $1 or ${name}.
+func findReplacements(value interface{}, re *regexp.Regexp, template string, useRegex, keys bool) []replacement {
+	replace := func(text string) string {
+		if useRegex {
+			return re.ReplaceAllString(text, template)
+		}
+		return re.ReplaceAllLiteralString(text, template)
+	}
+
+	var replacements []replacement
+	var walk func(path jsonPath, value interface{})
+	walk = func(path jsonPath, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			keyNames := make([]string, 0, len(v))
+			for key := range v {
+				keyNames = append(keyNames, key)
+			}
+			sort.Strings(keyNames)
+			for _, key := range keyNames {
+				if keys && re.MatchString(key) {
+					if newKey := replace(key); newKey != key {
+						replacements = append(replacements, replacement{path: path.child(key), key: true, oldText: key, newText: newKey, accepted: true})
+					}
+				}
+				walk(path.child(key), v[key])
+			}
+		case []interface{}:
+			for i, child := range v {
+				walk(path.child(i), child)
+			}
+		case string:
+			if re.MatchString(v) {
+				if newText := replace(v); newText != v {
+					replacements = append(replacements, replacement{path: path, oldText: v, newText: newText, accepted: true})
+				}
+			}
+		}
+	}
+	walk(nil, value)
+	return replacements
+}
+
+// findSpans locates the string values and keys of the document in text. A
+// JSON Lines document (isLines) is scanned line by line, its records indexed
+// like the array parseDocument makes of them.
+func findSpans(text []byte, isLines bool) (map[spanKey]tokenSpan, error) {
+	scanner := &spanScanner{data: text, spans: map[spanKey]tokenSpan{}}
+	if !isLines {
+		if err := scanner.value(nil); err != nil {
+			return nil, err
+		}
+		scanner.skipSpace()
+		if scanner.pos != len(text) {
+			return nil, fmt.Errorf("unexpected content at offset %d", scanner.pos)
+		}
+		return scanner.spans, nil
+	}
+
+	record := 0
+	for start := 0; start < len(text); {
+		end := bytes.IndexByte(text[start:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		if len(bytes.TrimSpace(text[start:end])) > 0 {
+			scanner.data, scanner.pos = text[:end], start
+			if err := scanner.value(jsonPath{record}); err != nil {
+				return nil, fmt.Errorf("record %d: %w", record+1, err)
+			}
+			record++
+		}
+		start = end + 1
+	}
+	return scanner.spans, nil
+}
+
+// lookupValue returns the value at path, or nil when the path does not exist
+func lookupValue(value interface{}, path jsonPath) interface{} {
+	for _, token := range path {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			key, _ := token.(string)
+			value = container[key]
+		case []interface{}:
+			index, ok := token.(int)
+			if !ok || index < 0 || index >= len(container) {
+				return nil
+			}
+			value = container[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// readDocumentText reads the text of a document file, decompressing .gz files
+func readDocumentText(file string) ([]byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil || !strings.HasSuffix(strings.ToLower(file), ".gz") {
+		return content, err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data in %s: %w", file, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// spliceEdits makes batches of replacements in the text of file in turn, each
+// in the text the batch before it left
+func spliceEdits(file string, text []byte, batches [][]replacement) ([]byte, error) {
+	name := strings.TrimSuffix(strings.ToLower(file), ".gz")
+	for _, batch := range batches {
+		var err error
+		if text, err = spliceReplacements(name, text, batch); err != nil {
+			return nil, err
+		}
+	}
+	return text, nil
+}
+
+// spliceReplacements makes the accepted replacements in the text of a
+// document, leaving everything else as it was: key order, formatting and
+// numbers are kept. name tells JSON Lines from JSON, as in parseDocument.
+func spliceReplacements(name string, text []byte, replacements []replacement) ([]byte, error) {
+	spans, err := findSpans(text, isJSONLines(name))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+
+	type edit struct {
+		span tokenSpan
+		text []byte
+	}
+	var edits []edit
+	for _, r := range replacements {
+		if !r.accepted {
+			continue
+		}
+		span, ok := spans[spanKey{r.path.pointer(), r.key}]
+		var current string
+		if ok {
+			ok = json.Unmarshal(text[span.start:span.end], &current) == nil && current == r.oldText
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s no longer holds %q at %s; reopen the file and try again", name, r.oldText, r.path)
+		}
+		encoded, err := encodeJSONString(r.newText)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %q: %w", r.newText, err)
+		}
+		edits = append(edits, edit{span, encoded})
+	}
+
+	// Splice from the end so the offsets of earlier tokens stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].span.start > edits[j].span.start })
+	result := append([]byte(nil), text...)
+	for _, e := range edits {
+		result = append(result[:e.span.start], append(e.text, result[e.span.end:]...)...)
+	}
+	return result, nil
+}
+
+// writeDocumentText writes the text of a document file, compressing .gz files
+func writeDocumentText(file string, text []byte) error {
+	if !strings.HasSuffix(strings.ToLower(file), ".gz") {
+		return writeFileAtomic(file, text)
+	}
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(text); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(file, buffer.Bytes())
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so readers never see a partly written file
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error syncing %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing %s: %w", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("error setting permissions of %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing %s: %w", path, err)
+	}
+	return nil
+}
+
+// skipSpace moves past whitespace
+func (s *spanScanner) skipSpace() {
+	for s.pos < len(s.data) && strings.IndexByte(" \t\r\n", s.data[s.pos]) >= 0 {
+		s.pos++
+	}
+}
+
+// string moves past a string token and returns its span
+func (s *spanScanner) string() (tokenSpan, error) {
+	start := s.pos
+	if s.pos >= len(s.data) || s.data[s.pos] != '"' {
+		return tokenSpan{}, fmt.Errorf("expected a string at offset %d", s.pos)
+	}
+	for s.pos++; s.pos < len(s.data); s.pos++ {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos++
+		case '"':
+			s.pos++
+			return tokenSpan{start, s.pos}, nil
+		}
+	}
+	return tokenSpan{}, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// value moves past the value at path, recording the spans of the strings in it
+func (s *spanScanner) value(path jsonPath) error {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return fmt.Errorf("unexpected end of input")
+	}
+	switch s.data[s.pos] {
+	case '"':
+		span, err := s.string()
+		if err != nil {
+			return err
+		}
+		s.spans[spanKey{path.pointer(), false}] = span
+	case '{':
+		s.pos++
+		for first := true; ; first = false {
+			s.skipSpace()
+			if s.pos < len(s.data) && s.data[s.pos] == '}' && first {
+				s.pos++
+				return nil
+			}
+			span, err := s.string()
+			if err != nil {
+				return err
+			}
+			var key string
+			if err := json.Unmarshal(s.data[span.start:span.end], &key); err != nil {
+				return fmt.Errorf("invalid key at offset %d: %w", span.start, err)
+			}
+			s.spans[spanKey{path.child(key).pointer(), true}] = span
+			s.skipSpace()
+			if s.pos >= len(s.data) || s.data[s.pos] != ':' {
+				return fmt.Errorf("expected ':' at offset %d", s.pos)
+			}
+			s.pos++
+			if err := s.value(path.child(key)); err != nil {
+				return err
+			}
+			s.skipSpace()
+			if s.pos < len(s.data) && s.data[s.pos] == ',' {
+				s.pos++
+				continue
+			}
+			if s.pos < len(s.data) && s.data[s.pos] == '}' {
+				s.pos++
+				return nil
+			}
+			return fmt.Errorf("expected ',' or '}' at offset %d", s.pos)
+		}
+	case '[':
+		s.pos++
+		for index := 0; ; index++ {
+			s.skipSpace()
+			if s.pos < len(s.data) && s.data[s.pos] == ']' && index == 0 {
+				s.pos++
+				return nil
+			}
+			if err := s.value(path.child(index)); err != nil {
+				return err
+			}
+			s.skipSpace()
+			if s.pos < len(s.data) && s.data[s.pos] == ',' {
+				s.pos++
+				continue
+			}
+			if s.pos < len(s.data) && s.data[s.pos] == ']' {
+				s.pos++
+				return nil
+			}
+			return fmt.Errorf("expected ',' or ']' at offset %d", s.pos)
+		}
+	default:
+		// Numbers and literals hold no strings; json.Unmarshal has validated them
+		for s.pos < len(s.data) && strings.IndexByte(",]} \t\r\n", s.data[s.pos]) < 0 {
+			s.pos++
+		}
+	}
+	return nil
+}
+
+// blockedByEdits reports whether the open document has unsaved edits that
+// would be lost by replacing it, asking to write or discard them first
+func (state *appState) blockedByEdits() bool {
+	if len(state.edits) == 0 {
+		return false
+	}
+	state.debugView.SetText("[yellow]" + tview.Escape(filepath.Base(state.activeFile)) + " has unsaved edits: press W to write them or U to discard them first.[-]")
+	return true
+}
+
+// discardEdits drops the unsaved edits of the open document and shows the
+// file as it is on disk again
+func (state *appState) discardEdits() {
+	if len(state.edits) == 0 {
+		state.debugView.SetText("No unsaved edits in this tab.")
+		return
+	}
+	state.edits = nil
+	state.refreshTabs()
+	state.reloadActiveFile()
+	state.debugView.SetText("Discarded the unsaved edits of " + tview.Escape(filepath.Base(state.activeFile)) + ".")
+}
+
+// replaceBlocked explains why the main pane cannot be written back, or
+// returns "" when it can: a timeline snapshot or a followed log is not the
+// file as it is on disk.
+func (state *appState) replaceBlocked() string {
+	if state.timelineVisible {
+		return "Close the timeline (T) before replacing: the main pane shows a snapshot, not the file."
+	}
+	if state.follow != nil {
+		return "Stop following (t) before replacing: the file is still being appended to."
+	}
+	return ""
+}
+
+// saveEdits writes the unsaved edits of the open document into its file,
+// which may have changed on disk since they were made as long as the
+// replaced text is still there
+func (state *appState) saveEdits() {
+	if len(state.edits) == 0 {
+		state.debugView.SetText("No unsaved edits in this tab.")
+		return
+	}
+	file := state.activeFile
+	text, err := readDocumentText(file)
+	if err == nil {
+		text, err = spliceEdits(file, text, state.edits)
+	}
+	if err == nil {
+		err = writeDocumentText(file, text)
+	}
+	if err != nil {
+		errorLogger.Printf("Failed to write replacements to %s: %v", file, err)
+		state.debugView.SetText("[red]Failed to write " + tview.Escape(file) + ": " + tview.Escape(err.Error()) + "[-]")
+		return
+	}
+	infoLogger.Printf("Wrote replacements to %s", file)
+	state.edits = nil
+	state.refreshTabs()
+	state.reloadActiveFile()
+	state.debugView.SetText("Wrote " + tview.Escape(file))
+}
+
+// showReplaceForm asks what to find and replace in the open file
+func (state *appState) showReplaceForm() {
+	if state.activeFile == "" || state.panes[mainPane].document.lines == nil {
+		state.debugView.SetText("Open a file with Enter to search and replace in it.")
+		return
+	}
+	if message := state.replaceBlocked(); message != "" {
+		state.debugView.SetText(message)
+		return
+	}
+
+	form := tview.NewForm()
+	fail := func(message string) {
+		form.SetTitle("Replace in " + tview.Escape(state.activeFile) + ": [red]" + tview.Escape(message) + "[-]")
+	}
+	form.AddInputField("Find", state.searchString, 50, nil, nil).
+		AddInputField("Replace with", "", 50, nil, nil).
+		AddCheckbox("Regex ($1 for groups)", state.searchOptions.useRegex, nil).
+		AddCheckbox("Case sensitive", state.searchOptions.caseSensitive, nil).
+		AddCheckbox("Include keys", false, nil).
+		AddButton("Preview", func() {
+			find := form.GetFormItem(0).(*tview.InputField).GetText()
+			template := form.GetFormItem(1).(*tview.InputField).GetText()
+			options := searchOptions{
+				useRegex:      form.GetFormItem(2).(*tview.Checkbox).IsChecked(),
+				caseSensitive: form.GetFormItem(3).(*tview.Checkbox).IsChecked(),
+			}
+			keys := form.GetFormItem(4).(*tview.Checkbox).IsChecked()
+			if find == "" {
+				fail("enter the text to find")
+				return
+			}
+			re, err := compileSearch(find, options)
+			if err != nil {
+				fail(err.Error())
+				return
+			}
+			replacements := findReplacements(state.panes[mainPane].document.value, re, template, options.useRegex, keys)
+			if len(replacements) == 0 {
+				fail("nothing to replace")
+				return
+			}
+			state.showReplacePreview(replacements)
+		}).
+		AddButton("Cancel", state.returnToMain).
+		SetCancelFunc(state.returnToMain)
+	form.SetBorder(true).SetTitle("Replace in " + tview.Escape(state.activeFile))
+
+	state.app.SetRoot(form, true).SetFocus(form)
+}
+
+// showReplacePreview lists the proposed replacements next to a diff of the
+// result. Each replacement can be accepted or rejected before writing.
+func (state *appState) showReplacePreview(replacements []replacement) {
+	file := state.activeFile
+	original := state.panes[mainPane].document.value
+	// The text as the document shows it: the file with the unsaved edits made
+	text, err := readDocumentText(file)
+	if err == nil {
+		text, err = spliceEdits(file, text, state.edits)
+	}
+	if err != nil {
+		errorLogger.Printf("Failed to read %s: %v", file, err)
+		state.debugView.SetText("[red]Failed to read " + tview.Escape(file) + ": " + tview.Escape(err.Error()) + "[-]")
+		return
+	}
+
+	list := tview.NewList().ShowSecondaryText(false).SetHighlightFullLine(true)
+	list.SetBorder(true).SetTitle("Replacements (Space: accept/reject, a: all, A: none, w: write, u: apply unsaved, Esc: cancel)")
+	diffView := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	diffView.SetBorder(true)
+
+	label := func(r replacement) string {
+		mark := "[green]✓[-]"
+		if !r.accepted {
+			mark = "[red]✗[-]"
+		}
+		kind := "value"
+		if r.key {
+			kind = "key"
+		}
+		return fmt.Sprintf("%s %s %s: %s → %s", mark, kind, tview.Escape(r.path.String()),
+			tview.Escape(summarizeValue(r.oldText)), tview.Escape(summarizeValue(r.newText)))
+	}
+	// applyReplacements catches renames onto existing keys; the spliced text
+	// is what gets written
+	result := func() ([]byte, error) {
+		if _, err := applyReplacements(original, replacements); err != nil {
+			return nil, err
+		}
+		return spliceEdits(file, text, [][]replacement{replacements})
+	}
+	refresh := func() {
+		accepted := 0
+		for i, r := range replacements {
+			list.SetItemText(i, label(r), "")
+			if r.accepted {
+				accepted++
+			}
+		}
+		after, err := result()
+		if err != nil {
+			diffView.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+			return
+		}
+		diffView.SetText(colorizeUnifiedDiff(unifiedDiff(file, file, string(text), string(after))))
+		diffView.SetTitle(fmt.Sprintf("Preview: %d of %d replacements accepted", accepted, len(replacements)))
+	}
+	setAll := func(accepted bool) {
+		for i := range replacements {
+			replacements[i].accepted = accepted
+		}
+		refresh()
+	}
+
+	for range replacements {
+		list.AddItem("", "", 0, nil)
+	}
+	refresh()
+
+	write := func() {
+		if message := state.replaceBlocked(); message != "" {
+			diffView.SetText("[red]" + tview.Escape(message) + "[-]")
+			return
+		}
+		content, err := result()
+		if err != nil {
+			return // refresh already shows the error in the preview
+		}
+		if err := writeDocumentText(file, content); err != nil {
+			errorLogger.Printf("Failed to write replacements to %s: %v", file, err)
+			diffView.SetText("[red]Failed to write " + tview.Escape(file) + ": " + tview.Escape(err.Error()) + "[-]")
+			return
+		}
+		infoLogger.Printf("Wrote replacements to %s", file)
+		state.edits = nil
+		state.returnToMain()
+		state.refreshTabs()
+		state.reloadActiveFile()
+		state.debugView.SetText("Wrote " + tview.Escape(file))
+	}
+	// keep shows the replacements in the document without writing them
+	keep := func() {
+		if _, err := result(); err != nil {
+			return // refresh already shows the error in the preview
+		}
+		updated, _ := applyReplacements(original, replacements)
+		var batch []replacement
+		for _, r := range replacements {
+			if r.accepted {
+				batch = append(batch, r)
+			}
+		}
+		state.returnToMain()
+		if len(batch) == 0 {
+			state.debugView.SetText("No replacements accepted.")
+			return
+		}
+		state.edits = append(state.edits, batch)
+		state.quitWarned = false
+		row, column := state.fileContent.GetScrollOffset()
+		state.setContentDocument(file, newFormattedDocument(updated, nil))
+		state.fileContent.SetTitle(filepath.Base(file))
+		state.fileContent.ScrollTo(row, column)
+		state.refreshTabs()
+		state.debugView.SetText(fmt.Sprintf("%d replacements made in %s, not written yet. Press W to write them or U to discard them.", len(batch), tview.Escape(filepath.Base(file))))
+	}
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			state.returnToMain()
+			state.debugView.SetText("Replace cancelled.")
+			return nil
+		case tcell.KeyEnter:
+			event = tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone)
+		}
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case ' ':
+			index := list.GetCurrentItem()
+			replacements[index].accepted = !replacements[index].accepted
+			refresh()
+		case 'a':
+			setAll(true)
+		case 'A':
+			setAll(false)
+		case 'w', 'W':
+			write()
+		case 'u':
+			keep()
+		default:
+			return event
+		}
+		return nil
+	})
+
+	layout := tview.NewFlex().
+		AddItem(list, 0, 1, true).
+		AddItem(diffView, 0, 1, false)
+	state.app.SetRoot(layout, true).SetFocus(list)
+}
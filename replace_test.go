@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSpliceReplacements(t *testing.T) {
+	tests := []struct {
+		name, file, text string
+		find, template   string
+		keys             bool
+		want             string
+	}{
+		{"keeps order, layout and numbers", "a.json",
+			"{\n  \"z\": \"old\",\n  \"a\": 12345678901234567890,\n  \"m\": [ \"old\", 1.50 ]\n}\n", "old", "new", false,
+			"{\n  \"z\": \"new\",\n  \"a\": 12345678901234567890,\n  \"m\": [ \"new\", 1.50 ]\n}\n"},
+		{"no HTML escaping", "a.json", `{"a":"x"}`, "x", "<&>", false, `{"a":"<&>"}`},
+		{"escaped text", "a.json", `{"a":"say \"hi\"!"}`, "hi", "bye", false, `{"a":"say \"bye\"!"}`},
+		{"keys", "a.json", `{"old":{"old":"old"}}`, "old", "new", true, `{"new":{"new":"new"}}`},
+		{"JSON Lines stay lines", "a.jsonl", "{\"a\":\"x\"}\n\n{\"a\":\"y\"}\n{\"a\":\"x\"}\n", "x", "z", false,
+			"{\"a\":\"z\"}\n\n{\"a\":\"y\"}\n{\"a\":\"z\"}\n"},
+		{"root string", "a.json", ` "x" `, "x", "y", false, ` "y" `},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := parseDocument(test.file, []byte(test.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			replacements := findReplacements(value, regexp.MustCompile(regexp.QuoteMeta(test.find)), test.template, false, test.keys)
+			got, err := spliceReplacements(test.file, []byte(test.text), replacements)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSpliceReplacementsChangedFile(t *testing.T) {
+	value, err := parseDocument("a.json", []byte(`{"a":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replacements := findReplacements(value, regexp.MustCompile("x"), "y", false, false)
+	if _, err := spliceReplacements("a.json", []byte(`{"a":"w"}`), replacements); err == nil {
+		t.Error("replacing text that is no longer in the file succeeded, want an error")
+	}
+}
+
+func TestSpliceEdits(t *testing.T) {
+	text := []byte("{\"old\": {\"x\": \"old\"}}\n")
+	value, err := parseDocument("a.json", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second batch is found in the document the first one left
+	first := findReplacements(value, regexp.MustCompile("old"), "new", false, true)
+	edited, err := applyReplacements(value, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := findReplacements(edited, regexp.MustCompile("new"), "<newer>", false, false)
+
+	got, err := spliceEdits("a.json.gz", text, [][]replacement{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"new\": {\"x\": \"<newer>\"}}\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	searchHistoryFile = "search_history"
+	maxSearchHistory  = 100
+	// Quiet time after the last keystroke in the search prompt before the
+	// search runs, so typing a word searches once rather than per letter
+	searchDebounce = 150 * time.Millisecond
+)
+
+// loadSearchHistory reads previous searches, oldest first
+func loadSearchHistory() ([]string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(dir, searchHistoryFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search history: %w", err)
+	}
+	defer file.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history = append(history, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading search history: %w", err)
+	}
+	return history, nil
+}
+
+func saveSearchHistory(history []string) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	content := strings.Join(history, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, searchHistoryFile), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to save search history: %w", err)
+	}
+	return nil
+}
+
+// finishSearch closes the search prompt, keeps the results and remembers the search
+func (state *appState) finishSearch() {
+	state.searchMode = false
+	state.statusRow.SwitchToPage("status")
+	pane := state.searchPane
+	if len(state.searchResults) > 0 {
+		pane = state.searchResults[state.currentSearchIndex].pane
+	}
+	if pane == comparePane && state.secondFileVisible {
+		state.focusComparePane()
+	} else {
+		state.focusContent()
+	}
+
+	if state.searchString == "" {
+		state.debugView.SetText("")
+		return
+	}
+	state.recordSearch(state.searchString)
+
+	if err := state.performSearch(); err != nil {
+		state.debugView.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+		return
+	}
+	if len(state.searchResults) == 0 {
+		state.debugView.SetText("[red]No results found for: " + tview.Escape(state.searchString) + " (" + state.searchTarget() + ")[-]")
+		return
+	}
+	state.showResultCounter()
+}
+
+// recallSearchHistory moves through previous searches; step is -1 for older, +1 for newer
+func (state *appState) recallSearchHistory(step int) {
+	if state.historyIndex == len(state.searchHistory) {
+		state.searchDraft = state.searchInput.GetText()
+	}
+	index := state.historyIndex + step
+	if index < 0 || index > len(state.searchHistory) {
+		return
+	}
+	state.historyIndex = index
+	if index == len(state.searchHistory) {
+		state.searchInput.SetText(state.searchDraft)
+	} else {
+		state.searchInput.SetText(state.searchHistory[index])
+	}
+}
+
+// recordSearch appends text to the search history, moving repeated searches to the end
+func (state *appState) recordSearch(text string) {
+	history := state.searchHistory[:0:0]
+	for _, previous := range state.searchHistory {
+		if previous != text {
+			history = append(history, previous)
+		}
+	}
+	history = append(history, text)
+	if len(history) > maxSearchHistory {
+		history = history[len(history)-maxSearchHistory:]
+	}
+	state.searchHistory = history
+
+	if err := saveSearchHistory(history); err != nil {
+		errorLogger.Printf("Failed to save search history: %v", err)
+	}
+}
+
+// refreshSearch re-runs the search as the prompt changes, reporting
+// invalid patterns next to the prompt
+func (state *appState) refreshSearch() {
+	if err := state.performSearch(); err != nil {
+		state.searchInput.SetLabel(fmt.Sprintf("Search (%s): ", state.searchTarget()))
+		state.searchInfo.SetText("[red]" + tview.Escape(err.Error()) + "[-]")
+		return
+	}
+	state.updateSearchPrompt()
+}
+
+func (state *appState) setupSearchInput() {
+	history, err := loadSearchHistory()
+	if err != nil {
+		errorLogger.Printf("Failed to load search history: %v", err)
+	}
+	state.searchHistory = history
+
+	state.searchInput.SetChangedFunc(func(text string) {
+		state.searchString = text
+		if state.searchTimer != nil {
+			state.searchTimer.Stop()
+		}
+		state.searchTimer = time.AfterFunc(searchDebounce, func() {
+			state.app.QueueUpdateDraw(func() {
+				// Enter and Escape search, or clear the search, right away
+				if state.searchMode && state.searchString == text {
+					state.refreshSearch()
+				}
+			})
+		})
+	})
+	state.searchInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyUp:
+			state.recallSearchHistory(-1)
+			return nil
+		case tcell.KeyDown:
+			state.recallSearchHistory(1)
+			return nil
+		case tcell.KeyRune:
+			if event.Modifiers()&tcell.ModAlt == 0 {
+				return event
+			}
+			switch event.Rune() {
+			case 'c', 'C':
+				state.searchOptions.caseSensitive = !state.searchOptions.caseSensitive
+			case 'r', 'R':
+				state.searchOptions.useRegex = !state.searchOptions.useRegex
+			case 'b', 'B':
+				state.searchBothPanes = !state.searchBothPanes
+			default:
+				return event
+			}
+			state.refreshSearch()
+			return nil
+		}
+		return event
+	})
+	state.searchInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			state.finishSearch()
+		case tcell.KeyEscape:
+			state.cancelSearch()
+		}
+	})
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// documentTab is an open document kept in the background while another tab
+// is shown, with the place and the search it was left at, what its compare
+// pane showed and the edits not written to its file yet. The shown tab lives
+// in the fields of appState and is only stored here when left. Documents are
+// always shown fully expanded, so a tab has no fold state; the directories
+// expanded in the file tree are shared by all tabs.
+type documentTab struct {
+	file                      string
+	document                  formattedDocument
+	row, column               int
+	searchString              string
+	searchOptions             searchOptions
+	currentSearchIndex        int
+	searchPane                int
+	searchBothPanes           bool
+	filterView                bool
+	compareVisible            bool
+	compareFile               string // "" when the compare pane shows a revision or snapshot
+	compareTitle              string
+	compareDocument           formattedDocument
+	compareRow, compareColumn int
+	comparison                *comparison
+	edits                     [][]replacement
+	stale                     bool // the file changed on disk while the tab was in the background
+}
+
+// closeTab closes the shown tab and shows the one after it, or before it
+// when it was the last
+func (state *appState) closeTab() {
+	if len(state.tabs) < 2 {
+		state.debugView.SetText("The last tab cannot be closed.")
+		return
+	}
+	if state.blockedByEdits() {
+		return
+	}
+	closed := state.activeFile
+	state.leaveTab()
+	state.tabs = append(state.tabs[:state.currentTab], state.tabs[state.currentTab+1:]...)
+	if state.currentTab == len(state.tabs) {
+		state.currentTab--
+	}
+	state.showTab(state.currentTab)
+	if closed != "" {
+		state.debugView.SetText("Closed " + tview.Escape(filepath.Base(closed)) + ".")
+	}
+}
+
+// leaveTab stores the shown document and its place in the current tab
+// before another tab is shown
+func (state *appState) leaveTab() {
+	state.cancelLoad(loadMain)
+	state.cancelLoad(loadCompare)
+	state.cancelLoad(loadWatch)
+	state.stopFollow()
+	if state.timelineVisible {
+		state.toggleTimeline()
+	}
+	row, column := state.fileContent.GetScrollOffset()
+	tab := documentTab{
+		file:               state.activeFile,
+		document:           state.panes[mainPane].document,
+		row:                row,
+		column:             column,
+		searchString:       state.searchString,
+		searchOptions:      state.searchOptions,
+		currentSearchIndex: state.currentSearchIndex,
+		searchPane:         state.searchPane,
+		searchBothPanes:    state.searchBothPanes,
+		filterView:         state.filterView,
+		compareVisible:     state.secondFileVisible,
+		edits:              state.edits,
+		stale:              state.tabs[state.currentTab].stale,
+	}
+	if state.secondFileVisible {
+		tab.compareFile = state.compareFile
+		tab.compareTitle = state.secondFileContent.GetTitle()
+		tab.compareDocument = state.panes[comparePane].document
+		tab.compareRow, tab.compareColumn = state.secondFileContent.GetScrollOffset()
+		tab.comparison = state.comparison
+	}
+	state.tabs[state.currentTab] = tab
+}
+
+// markActiveFile highlights the open file in the file list
+func (state *appState) markActiveFile() {
+	state.activeFileIndex = -1
+	for i, listed := range state.files {
+		if listed == state.activeFile {
+			state.activeFileIndex = i
+		}
+	}
+	updateActiveFileHighlight(state.fileList, state.activeFileIndex)
+}
+
+// openInNewTab opens the file selected in the file list in a new tab after
+// the current one, which starts without a search
+func (state *appState) openInNewTab() {
+	index := state.fileList.GetCurrentItem()
+	if index >= len(state.files) || state.files[index] == "" {
+		state.debugView.SetText("Select a file to open it in a new tab.")
+		return
+	}
+	file := state.files[index]
+	if file == state.activeFile {
+		state.debugView.SetText(tview.Escape(filepath.Base(file)) + " is open in this tab.")
+		return
+	}
+	if state.switchToFile(file) {
+		return
+	}
+
+	state.leaveTab()
+	state.tabs = append(state.tabs[:state.currentTab+1], append([]documentTab{{}}, state.tabs[state.currentTab+1:]...)...)
+	state.showTab(state.currentTab + 1)
+	state.openFile(file, nil)
+}
+
+// unsavedTabs counts the tabs with unsaved edits
+func (state *appState) unsavedTabs() int {
+	count := 0
+	for i, tab := range state.tabs {
+		if (i == state.currentTab && len(state.edits) > 0) || (i != state.currentTab && len(tab.edits) > 0) {
+			count++
+		}
+	}
+	return count
+}
+
+// refreshTabs shows the tab bar above the panes while more than one tab is
+// open or a tab has unsaved edits, which are marked with a dot
+func (state *appState) refreshTabs() {
+	unsaved := state.unsavedTabs() > 0
+	if len(state.tabs) < 2 && !unsaved {
+		if state.tabBarVisible {
+			state.tabBarVisible = false
+			state.arrangeRows()
+		}
+		return
+	}
+
+	var text strings.Builder
+	for i, tab := range state.tabs {
+		file, edits := tab.file, tab.edits
+		if i == state.currentTab {
+			file, edits = state.activeFile, state.edits
+		}
+		name := "(empty)"
+		if file != "" {
+			name = filepath.Base(file)
+		}
+		if len(edits) > 0 {
+			name += " ●"
+		}
+		label := fmt.Sprintf(" %d %s ", i+1, tview.Escape(name))
+		switch {
+		case i == state.currentTab:
+			fmt.Fprintf(&text, "[black:green]%s[-:-]", label)
+		case tab.stale:
+			fmt.Fprintf(&text, "[yellow]%s[-]", label)
+		default:
+			text.WriteString(label)
+		}
+		text.WriteString("│")
+	}
+	help := " [gray][/]: switch, w: close, a: open in new tab[-]"
+	if unsaved {
+		help = " [gray]●: unsaved edits, W: write, U: discard[-]" + help
+	}
+	state.tabBar.SetText(text.String() + help)
+	if !state.tabBarVisible {
+		state.tabBarVisible = true
+		state.arrangeRows()
+	}
+}
+
+// restoreComparePane shows or hides the compare pane as tab left it
+func (state *appState) restoreComparePane(tab documentTab) {
+	if !tab.compareVisible {
+		if state.secondFileVisible {
+			state.mainFlex.RemoveItem(state.secondFileContent)
+			state.secondFileVisible = false
+		}
+		state.panes[comparePane].document = formattedDocument{}
+		state.compareFile = ""
+		state.comparison = nil
+		return
+	}
+
+	if state.secondFileContent == nil {
+		state.secondFileContent = tview.NewTextView().SetDynamicColors(true).SetRegions(true).SetWrap(true).SetScrollable(true)
+		state.secondFileContent.SetBorder(true).SetBorderColor(tcell.ColorGray)
+	}
+	if !state.secondFileVisible {
+		state.mainFlex.AddItem(state.secondFileContent, 0, 2, false)
+		state.secondFileVisible = true
+	}
+	state.panes[comparePane] = contentPane{view: state.secondFileContent, document: tab.compareDocument}
+	state.secondFileContent.SetTitle(tab.compareTitle)
+	state.compareFile = tab.compareFile
+	state.comparison = tab.comparison
+}
+
+// showTab shows a stored tab with its search, scroll position and compare
+// pane. A tab whose file changed on disk meanwhile is reloaded.
+func (state *appState) showTab(index int) {
+	tab := state.tabs[index]
+	state.currentTab = index
+	state.searchString = tab.searchString
+	state.searchOptions = tab.searchOptions
+	state.searchPane = tab.searchPane
+	state.searchBothPanes = tab.searchBothPanes
+	state.filterView = tab.filterView
+	state.edits = tab.edits
+	state.restoreComparePane(tab)
+
+	state.setContentDocument(tab.file, tab.document)
+	state.fileContent.SetTitle(filepath.Base(tab.file))
+	if tab.file == "" {
+		state.fileContent.SetTitle("Content")
+	}
+	if tab.currentSearchIndex < len(state.searchResults) {
+		state.currentSearchIndex = tab.currentSearchIndex
+		state.highlightCurrentResult()
+	}
+	state.fileContent.ScrollTo(tab.row, tab.column)
+	if tab.compareVisible {
+		state.secondFileContent.ScrollTo(tab.compareRow, tab.compareColumn)
+	}
+	state.markActiveFile()
+	if len(state.searchResults) > 0 {
+		state.showResultCounter()
+	} else {
+		state.debugView.SetText(fmt.Sprintf("Tab %d of %d.", index+1, len(state.tabs)))
+	}
+
+	if tab.stale {
+		state.tabs[index].stale = false
+		state.reloadActiveFile()
+	}
+	if tab.compareVisible && tab.compareFile != "" && (tab.stale || tab.compareDocument.lines == nil) {
+		state.loadComparePane() // Diff against the new version, or finish a load cut short
+	}
+	state.refreshTabs()
+}
+
+// switchTab shows the tab offset places after the current one, wrapping around
+func (state *appState) switchTab(offset int) {
+	if len(state.tabs) < 2 {
+		state.debugView.SetText("Press a on a file to open it in a new tab.")
+		return
+	}
+	state.leaveTab()
+	state.showTab((state.currentTab + offset + len(state.tabs)) % len(state.tabs))
+}
+
+// switchToFile shows the tab holding file, reporting whether there is one
+func (state *appState) switchToFile(file string) bool {
+	for i, tab := range state.tabs {
+		if i != state.currentTab && tab.file == file {
+			state.leaveTab()
+			state.showTab(i)
+			return true
+		}
+	}
+	return false
+}
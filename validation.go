@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Files larger than this are not parsed in the background
+const validationSizeLimit = 64 << 20
+
+// fileStatus is the outcome of validating a file, in the order sorting by
+// status lists them: problems first
+type fileStatus int
+
+const (
+	statusInvalid fileStatus = iota
+	statusUnreadable
+	statusDeleted
+	statusTooLarge
+	statusValid
+	statusPending
+)
+
+// Badges shown after a file in the file list
+var statusBadges = map[fileStatus]string{
+	statusInvalid:    "[red]✗ invalid[-]",
+	statusUnreadable: "[red]! unreadable[-]",
+	statusDeleted:    "[red]⌫ deleted[-]",
+	statusTooLarge:   "[yellow]⚠ too large[-]",
+	statusValid:      "[green]✓[-]",
+	statusPending:    "[gray]…[-]",
+}
+
+// fileValidation is what background validation found out about a file.
+// The shape of the document is only known for valid files.
+type fileValidation struct {
+	status  fileStatus
+	size    int64
+	modTime time.Time
+	topType string // type of the top-level value
+	count   int    // keys or elements of a top-level object or array
+	depth   int    // deepest nesting of objects and arrays
+	err     error
+}
+
+// formatSize renders a byte count for the file list
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, prefix := float64(size)/unit, 0
+	for value >= unit && prefix < 3 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[prefix])
+}
+
+// validateFile checks that a file can be read and parsed
+func validateFile(file string) fileValidation {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileValidation{status: statusUnreadable, err: err}
+	}
+	validation := fileValidation{size: info.Size(), modTime: info.ModTime()}
+	if info.Size() > validationSizeLimit {
+		validation.status = statusTooLarge
+		validation.err = fmt.Errorf("larger than %s", formatSize(validationSizeLimit))
+		return validation
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		validation.status, validation.err = statusUnreadable, err
+		return validation
+	}
+	value, err := parseDocument(file, content)
+	if err != nil {
+		validation.status, validation.err = statusInvalid, err
+		return validation
+	}
+
+	validation.status = statusValid
+	validation.depth = valueDepth(value)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validation.topType, validation.count = "object", len(v)
+	case []interface{}:
+		validation.topType, validation.count = "array", len(v)
+	default:
+		for _, name := range []string{"null", "bool", "number", "string"} {
+			if matchesType(value, name) {
+				validation.topType = name
+			}
+		}
+	}
+	return validation
+}
+
+// validateFiles validates files on a pool of goroutines and calls done with
+// the outcome for each, from the worker that validated it
+func validateFiles(ctx context.Context, files []string, done func(file string, validation fileValidation)) {
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range paths {
+				validation := validateFile(file)
+				if ctx.Err() == nil {
+					done(file, validation)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, file := range files {
+		select {
+		case paths <- file:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(paths)
+	wg.Wait()
+}
+
+// valueDepth is the deepest nesting of objects and arrays in value
+func valueDepth(value interface{}) int {
+	depth := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			if d := valueDepth(child); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	case []interface{}:
+		for _, child := range v {
+			if d := valueDepth(child); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	}
+	return depth
+}
+
+// countInvalid counts the listed files that failed to parse
+func (state *appState) countInvalid() int {
+	count := 0
+	for _, validation := range state.validations {
+		if validation.status == statusInvalid {
+			count++
+		}
+	}
+	return count
+}
+
+// fileStatus returns the validation status of a listed file
+func (state *appState) fileStatus(file string) fileStatus {
+	if validation, ok := state.validations[file]; ok {
+		return validation.status
+	}
+	return statusPending
+}
+
+// runValidation validates the files a walk found and passes the outcomes to
+// the file list in batches, dropping them if a newer walk started since
+func (state *appState) runValidation(ctx context.Context, generation int, files []string) {
+	var mu sync.Mutex
+	results := map[string]fileValidation{}
+	lastFlush := time.Now()
+	flush := func() {
+		batch := results
+		results = map[string]fileValidation{}
+		state.app.QueueUpdateDraw(func() {
+			if generation != state.walkGeneration {
+				return
+			}
+			for file, validation := range batch {
+				if current, ok := state.validations[file]; ok && current.modTime.After(validation.modTime) {
+					continue // A later change was validated first
+				}
+				state.validations[file] = validation
+			}
+			state.refreshFileList()
+		})
+	}
+
+	validateFiles(ctx, files, func(file string, validation fileValidation) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[file] = validation
+		if time.Since(lastFlush) >= walkFlushInterval {
+			flush()
+			lastFlush = time.Now()
+		}
+	})
+	flush()
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rivo/tview"
+)
+
+const (
+	// Quiet time after the last filesystem event before changes are applied,
+	// so an editor saving through several writes causes one reload
+	watchDebounce = 200 * time.Millisecond
+	// How often the directory is re-walked when filesystem events are not available
+	pollInterval = 2 * time.Second
+)
+
+// fileStamp is what polling compares to notice that a file changed
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// pollFiles re-walks root every pollInterval and passes the paths of files
+// that appeared, disappeared or changed size or modification time to changed
+func pollFiles(ctx context.Context, root string, options discoveryOptions, changed func(paths []string)) {
+	stamps := func() map[string]fileStamp {
+		found := map[string]fileStamp{}
+		walkDocuments(ctx, root, options, func(path string, dir bool) {
+			if info, err := os.Stat(path); err == nil && !dir {
+				found[path] = fileStamp{info.Size(), info.ModTime()}
+			}
+		}, func(walkFailure) {})
+		return found
+	}
+
+	previous := stamps()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := stamps()
+		if ctx.Err() != nil {
+			return
+		}
+		var paths []string
+		for file, stamp := range current {
+			if old, ok := previous[file]; !ok || old != stamp {
+				paths = append(paths, file)
+			}
+		}
+		for file := range previous {
+			if _, ok := current[file]; !ok {
+				paths = append(paths, file)
+			}
+		}
+		previous = current
+		if len(paths) > 0 {
+			changed(paths)
+		}
+	}
+}
+
+// watchDirectories passes the paths that filesystem events report under the
+// directories dirs of root to changed, in batches once events settle.
+// Directories created while watching are watched too if the discovery
+// options select them, and the files already in them reported. It returns an
+// error if the directories cannot be watched.
+func watchDirectories(ctx context.Context, root string, options discoveryOptions, dirs []string, changed func(paths []string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating watcher: %w", err)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("error watching %s: %w", dir, err)
+		}
+	}
+
+	d := &discovery{root: root, options: options, ignores: map[string][]gitignoreRule{}}
+	go func() {
+		defer watcher.Close()
+		pending := map[string]bool{}
+		timer := time.NewTimer(watchDebounce)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name) // Match the paths the walk lists
+				pending[name] = true
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(name); err == nil && info.IsDir() {
+						// Files may have been written before the watch was added
+						filepath.WalkDir(name, func(path string, entry os.DirEntry, err error) error {
+							if err != nil {
+								return nil
+							}
+							if !entry.IsDir() {
+								pending[path] = true
+								return nil
+							}
+							if rel, err := filepath.Rel(root, path); err != nil || !d.selects(filepath.ToSlash(rel), true) {
+								return filepath.SkipDir
+							}
+							if err := watcher.Add(path); err != nil {
+								errorLogger.Printf("Failed to watch %s: %v", path, err)
+							}
+							return nil
+						})
+					}
+				}
+				timer.Reset(watchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				errorLogger.Printf("File watcher error: %v", err)
+			case <-timer.C:
+				paths := make([]string, 0, len(pending))
+				for path := range pending {
+					paths = append(paths, path)
+				}
+				pending = map[string]bool{}
+				changed(paths)
+			}
+		}
+	}()
+	return nil
+}
+
+// applyFileChanges brings the file list and the open files up to date with
+// paths that changed on disk: new files are listed, deleted ones marked, and
+// modified ones re-rendered in place if open. New and modified files are
+// validated again in the background.
+func (state *appState) applyFileChanges(ctx context.Context, generation int, paths []string) {
+	d := &discovery{root: state.rootDir, options: state.discovery, ignores: map[string][]gitignoreRule{}}
+	listed := make(map[string]bool, len(state.allFiles))
+	for _, file := range state.allFiles {
+		listed[file] = true
+	}
+
+	var modified, added, deleted, revalidate []string
+	sort.Strings(paths)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		exists := err == nil && !info.IsDir()
+		switch {
+		case exists && listed[path]:
+			revalidate = append(revalidate, path)
+			if state.follow != nil && state.follow.file == path {
+				continue // Follow mode shows what was appended
+			}
+			modified = append(modified, path)
+		case exists:
+			rel, err := filepath.Rel(state.rootDir, path)
+			if err != nil || strings.HasPrefix(rel, "..") || !d.selects(filepath.ToSlash(rel), false) {
+				continue
+			}
+			added = append(added, path)
+			state.allFiles = append(state.allFiles, path)
+			revalidate = append(revalidate, path)
+		case listed[path] && errors.Is(err, os.ErrNotExist):
+			if state.fileStatus(path) == statusDeleted {
+				continue
+			}
+			deleted = append(deleted, path)
+			state.validations[path] = fileValidation{status: statusDeleted, err: errors.New("deleted since it was listed")}
+		}
+	}
+	for _, path := range revalidate {
+		delete(state.validations, path) // Pending until the pool gets to it
+	}
+	if len(revalidate) > 0 {
+		go state.runValidation(ctx, generation, revalidate)
+	}
+	state.refreshFileList()
+	if len(modified)+len(added)+len(deleted) == 0 {
+		return
+	}
+
+	for _, file := range modified {
+		for i := range state.tabs {
+			if i != state.currentTab && state.tabs[i].file == file {
+				state.tabs[i].stale = true
+			}
+		}
+		if file == state.activeFile {
+			state.reloadActiveFile()
+		}
+		if file == state.compareFile && state.secondFileVisible {
+			state.loadComparePane()
+		}
+	}
+
+	state.refreshTabs()
+
+	describe := func(verb string, files []string) string {
+		if len(files) == 1 {
+			return verb + " " + tview.Escape(relativePath(state.rootDir, files[0]))
+		}
+		return fmt.Sprintf("%s %d files", verb, len(files))
+	}
+	var notices []string
+	if len(modified) > 0 {
+		notices = append(notices, describe("changed", modified))
+	}
+	if len(added) > 0 {
+		notices = append(notices, describe("added", added))
+	}
+	if len(deleted) > 0 {
+		notices = append(notices, describe("deleted", deleted))
+	}
+	infoLogger.Printf("Files changed on disk: %d modified, %d added, %d deleted", len(modified), len(added), len(deleted))
+	notice := strings.Join(notices, ", ")
+	state.debugView.SetText("[yellow]On disk: " + strings.ToUpper(notice[:1]) + notice[1:] + "[-]")
+}
+
+// reloadActiveFile re-parses the open file in the background, takes a
+// snapshot of it and renders it again, keeping the scroll position of the
+// content pane. The file is not rendered if another one was opened meanwhile.
+func (state *appState) reloadActiveFile() {
+	file := state.activeFile
+	checkSnapshot := state.prepareSnapshot(file)
+	state.runLoad(loadWatch, filepath.Base(file), func(ctx context.Context, job *loadJob) (func(), error) {
+		value, err := job.readFile(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		job.phase.Store(loadFormatting)
+		document := newFormattedDocument(value, nil)
+		candidate := checkSnapshot(value, job.size.Load())
+
+		return func() {
+			state.recordSnapshot(file, candidate)
+			if state.activeFile != file {
+				return
+			}
+			if state.timelineVisible && state.timelineFile == file {
+				return // Keep the snapshots being compared on screen
+			}
+			if len(state.edits) > 0 {
+				state.debugView.SetText("[yellow]" + tview.Escape(filepath.Base(file)) + " changed on disk. Its unsaved edits are kept: W writes them into the new version, U shows it.[-]")
+				return
+			}
+			row, column := state.fileContent.GetScrollOffset()
+			state.setContentDocument(file, document)
+			state.fileContent.SetTitle(filepath.Base(file))
+			state.fileContent.ScrollTo(row, column)
+		}, nil
+	})
+}
+
+// watchFiles keeps the file list in step with the disk after a walk of root
+// that entered dirs, using filesystem events where available and polling
+// otherwise. Changes found after a newer walk started are dropped.
+func (state *appState) watchFiles(ctx context.Context, generation int, root string, options discoveryOptions, dirs []string) {
+	changed := func(paths []string) {
+		state.app.QueueUpdateDraw(func() {
+			if generation == state.walkGeneration {
+				state.applyFileChanges(ctx, generation, paths)
+			}
+		})
+	}
+	if err := watchDirectories(ctx, root, options, dirs, changed); err != nil {
+		errorLogger.Printf("Falling back to polling for changes: %v", err)
+		go pollFiles(ctx, root, options, changed)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// startTestApp runs the viewer on a simulated screen. The func it returns
+// runs f on the UI goroutine and waits for it.
+func startTestApp(t *testing.T) (*appState, func(f func())) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	infoLogger = log.New(io.Discard, "", 0)
+	errorLogger = log.New(io.Discard, "", 0)
+
+	state := initializeApp()
+	setupLayout(state)
+	screen := tcell.NewSimulationScreen("")
+	screen.SetSize(120, 40)
+	state.app.SetScreen(screen).SetRoot(state.rootLayout, true)
+	stopped := make(chan struct{})
+	go func() {
+		if err := state.app.Run(); err != nil {
+			t.Error(err)
+		}
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		state.app.Stop()
+		<-stopped
+	})
+
+	ui := func(f func()) {
+		done := make(chan struct{})
+		state.app.QueueUpdateDraw(func() {
+			f()
+			close(done)
+		})
+		<-done
+	}
+	return state, ui
+}
+
+// waitForLoads waits until no load of state is running
+func waitForLoads(t *testing.T, state *appState, ui func(f func())) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		busy := false
+		ui(func() {
+			for _, job := range state.loads {
+				busy = busy || job != nil
+			}
+		})
+		if !busy {
+			return
+		}
+	}
+	t.Fatal("loads did not finish")
+}
+
+func TestReloadActiveFileDuringOpen(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.json"), filepath.Join(dir, "second.json")
+	for _, file := range []string{first, second} {
+		if err := os.WriteFile(file, []byte(`{"a":1}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, ui := startTestApp(t)
+
+	// The file on screen changes on disk while the user is opening another
+	ui(func() {
+		state.setContentValue(first, map[string]interface{}{"a": 0.0})
+		state.openFile(second, nil)
+		state.reloadActiveFile()
+	})
+	waitForLoads(t, state, ui)
+	ui(func() {
+		if state.activeFile != second {
+			t.Errorf("active file = %s, want %s", state.activeFile, second)
+		}
+	})
+}